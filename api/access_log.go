@@ -0,0 +1,64 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// secretQueryParams — параметры запроса с секретами, которые не пишутся в журнал
+// запросов. Токен ленты календаря передаётся в ссылке: клиенты календаря не умеют
+// слать Authorization.
+var secretQueryParams = []string{"token"}
+
+// AccessLog — журнал запросов в формате gin.Logger, в котором значения секретных
+// параметров запроса заменены на REDACTED.
+func AccessLog() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		p.Path = redactQuery(p.Path)
+
+		var statusColor, methodColor, resetColor string
+		if p.IsOutputColor() {
+			statusColor, methodColor, resetColor = p.StatusCodeColor(), p.MethodColor(), p.ResetColor()
+		}
+		if p.Latency > time.Minute {
+			p.Latency = p.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+			p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			statusColor, p.StatusCode, resetColor,
+			p.Latency,
+			p.ClientIP,
+			methodColor, p.Method, resetColor,
+			p.Path,
+			p.ErrorMessage,
+		)
+	})
+}
+
+// redactQuery заменяет в пути с запросом значения секретных параметров.
+func redactQuery(path string) string {
+	base, query, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		name, _, _ := strings.Cut(param, "=")
+		// gin декодирует имена параметров, поэтому tok%65n — тоже token
+		decoded, err := url.QueryUnescape(name)
+		if err != nil {
+			decoded = name
+		}
+		for _, secret := range secretQueryParams {
+			if decoded == secret {
+				params[i] = name + "=REDACTED"
+			}
+		}
+	}
+	return base + "?" + strings.Join(params, "&")
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/subscriptions", "/subscriptions"},
+		{"/subscriptions?limit=10", "/subscriptions?limit=10"},
+		{"/users/1/renewals.ics?token=cal_secret", "/users/1/renewals.ics?token=REDACTED"},
+		{"/users/1/renewals.ics?a=1&token=cal_secret&b=2", "/users/1/renewals.ics?a=1&token=REDACTED&b=2"},
+		{"/users/1/renewals.ics?tok%65n=cal_secret", "/users/1/renewals.ics?tok%65n=REDACTED"},
+		{"/users/1/renewals.ics?token", "/users/1/renewals.ics?token=REDACTED"},
+		{"/users/1/renewals.ics?tokens=1", "/users/1/renewals.ics?tokens=1"},
+	}
+	for _, tt := range tests {
+		if got := redactQuery(tt.path); got != tt.want {
+			t.Errorf("redactQuery(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestAccessLogOmitsFeedToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var out bytes.Buffer
+	prev := gin.DefaultWriter
+	gin.DefaultWriter = &out
+	t.Cleanup(func() { gin.DefaultWriter = prev })
+
+	r := gin.New()
+	r.Use(AccessLog())
+	r.GET("/users/:id/renewals.ics", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1/renewals.ics?token=cal_secret", nil))
+
+	if strings.Contains(out.String(), "cal_secret") {
+		t.Errorf("access log contains the token: %s", out.String())
+	}
+	if !strings.Contains(out.String(), "token=REDACTED") {
+		t.Errorf("access log = %q, want the redacted path", out.String())
+	}
+}
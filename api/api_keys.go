@@ -0,0 +1,145 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"subscriptions-go/service"
+)
+
+type APIKeyHandler struct {
+	svc *service.APIKeyService
+	log *logrus.Logger
+}
+
+func NewAPIKeyHandler(svc *service.APIKeyService, log *logrus.Logger) *APIKeyHandler {
+	return &APIKeyHandler{svc: svc, log: log}
+}
+
+type apiKeyReq struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"` // например subscriptions:read, subscriptions:read_all
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`      // RFC 3339; без срока — бессрочный
+}
+
+// @Summary      Create an API key
+// @Description  Выпускает API-ключ для сервисного клиента. Ключ действует от имени выпустившего его пользователя с разрешениями scopes. Секрет (key) возвращается только в этом ответе
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Param        X-Tenant-ID  header  string     false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        key          body    apiKeyReq  true   "API key"
+// @Success      201  {object}  service.IssuedAPIKey
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /api-keys [post]
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var r apiKeyReq
+	if err := c.ShouldBindJSON(&r); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	key, err := h.svc.Create(c.Request.Context(), r.Name, r.Scopes, r.ExpiresAt)
+	if err != nil {
+		fail(c, h.log, "create api key", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// @Summary      List API keys
+// @Description  Список API-ключей организации без секретов
+// @Tags         api-keys
+// @Produce      json
+// @Param        X-Tenant-ID  header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Success      200  {array}   model.APIKey
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /api-keys [get]
+func (h *APIKeyHandler) List(c *gin.Context) {
+	keys, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		fail(c, h.log, "list api keys", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// @Summary      Rotate an API key
+// @Description  Выпускает новый секрет ключа; прежний перестаёт действовать. Секрет возвращается только в этом ответе
+// @Tags         api-keys
+// @Produce      json
+// @Param        X-Tenant-ID  header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        id           path    string  true   "API key ID"
+// @Success      200  {object}  service.IssuedAPIKey
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /api-keys/{id}/rotate [post]
+func (h *APIKeyHandler) Rotate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+
+	key, err := h.svc.Rotate(c.Request.Context(), id)
+	if err != nil {
+		fail(c, h.log, "rotate api key", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// @Summary      Revoke an API key
+// @Description  Отзывает API-ключ
+// @Tags         api-keys
+// @Produce      json
+// @Param        X-Tenant-ID  header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        id           path    string  true   "API key ID"
+// @Success      200  {object}  model.APIKey
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /api-keys/{id} [delete]
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+
+	key, err := h.svc.Revoke(c.Request.Context(), id)
+	if err != nil {
+		fail(c, h.log, "revoke api key", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"subscriptions-go/repository"
+	"subscriptions-go/service"
+)
+
+type AuditHandler struct {
+	svc *service.AuditService
+	log *logrus.Logger
+}
+
+func NewAuditHandler(svc *service.AuditService, log *logrus.Logger) *AuditHandler {
+	return &AuditHandler{svc: svc, log: log}
+}
+
+// @Summary      Get subscription history
+// @Description  Журнал изменений подписки (в том числе удалённой) в хронологическом порядке
+// @Tags         audit
+// @Produce      json
+// @Param        X-Tenant-ID  header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        id           path    string  true   "Subscription ID"
+// @Success      200  {array}   model.AuditEntry
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /subscriptions/{id}/history [get]
+func (h *AuditHandler) History(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+
+	entries, err := h.svc.History(c.Request.Context(), id)
+	if err != nil {
+		fail(c, h.log, "history", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// @Summary      Search the audit log
+// @Description  Журнал изменений подписок организации, от новых записей к старым
+// @Tags         audit
+// @Produce      json
+// @Param        X-Tenant-ID      header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        subscription_id  query   string  false  "Filter by subscription ID"
+// @Param        actor            query   string  false  "Filter by actor"
+// @Param        action           query   string  false  "Filter by action: create, update, delete, restore, price_change, pause, resume"
+// @Param        request_id       query   string  false  "Filter by request ID"
+// @Param        from             query   string  false  "From time, RFC 3339 (inclusive)"
+// @Param        to               query   string  false  "To time, RFC 3339 (exclusive)"
+// @Param        limit            query   int     false  "Page size (default 50, max 500)"
+// @Param        cursor           query   string  false  "Cursor from next_cursor of the previous page"
+// @Success      200  {object}  repository.AuditPage
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /audit [get]
+func (h *AuditHandler) Search(c *gin.Context) {
+	var f repository.AuditFilter
+	if s := c.Query("subscription_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			badRequest(c, "subscription_id", "must be a UUID")
+			return
+		}
+		f.SubscriptionID = &id
+	}
+	if s := c.Query("actor"); s != "" {
+		f.Actor = &s
+	}
+	if s := c.Query("action"); s != "" {
+		f.Action = &s
+	}
+	if s := c.Query("request_id"); s != "" {
+		f.RequestID = &s
+	}
+
+	var ok bool
+	if f.From, ok = queryTime(c, "from"); !ok {
+		return
+	}
+	if f.To, ok = queryTime(c, "to"); !ok {
+		return
+	}
+
+	if l := c.Query("limit"); l != "" {
+		v, err := strconv.Atoi(l)
+		if err != nil || v < 1 || v > repository.MaxPageSize {
+			badRequest(c, "limit", fmt.Sprintf("must be between 1 and %d", repository.MaxPageSize))
+			return
+		}
+		f.Limit = v
+	}
+	f.Cursor = c.Query("cursor")
+
+	page, err := h.svc.Search(c.Request.Context(), f)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			badRequest(c, "cursor", err.Error())
+			return
+		}
+		fail(c, h.log, "audit", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// queryTime разбирает необязательный параметр запроса в формате RFC 3339.
+// При ошибке отвечает 400 и возвращает ok == false.
+func queryTime(c *gin.Context, name string) (*time.Time, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		badRequest(c, name, "must be an RFC 3339 time")
+		return nil, false
+	}
+	return &t, true
+}
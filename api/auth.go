@@ -0,0 +1,144 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/repository"
+	"subscriptions-go/service"
+)
+
+const (
+	codeUnauthorized = "unauthorized"
+
+	tenantHeader = "X-Tenant-ID"
+)
+
+// Require пропускает запрос, только если у вызывающего есть разрешение perm;
+// иначе отвечает 403. Должен стоять после Authenticate.
+func Require(perm auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := auth.FromContext(c.Request.Context())
+		if !ok {
+			unauthorized(c, "authentication required")
+			return
+		}
+		if !p.Can(perm) {
+			writeProblem(c, Problem{
+				Status: http.StatusForbidden,
+				Code:   service.CodeForbidden,
+				Detail: "missing permission " + string(perm),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Authenticate проверяет заголовок Authorization — Bearer <JWT> или ApiKey <ключ> —
+// и кладёт вызывающего в контекст запроса; без валидных учётных данных отвечает 401.
+func Authenticate(v *auth.Verifier, keys *service.APIKeyService, log *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, credentials, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		credentials = strings.TrimSpace(credentials)
+		if credentials == "" {
+			unauthorized(c, "missing credentials")
+			return
+		}
+
+		var p *auth.Principal
+		var err error
+		switch {
+		case strings.EqualFold(scheme, "Bearer"):
+			p, err = v.Verify(credentials)
+		case strings.EqualFold(scheme, "ApiKey"):
+			p, err = keys.Authenticate(c.Request.Context(), credentials)
+		default:
+			unauthorized(c, "unsupported authorization scheme")
+			return
+		}
+		if errors.Is(err, auth.ErrUnauthorized) {
+			unauthorized(c, "invalid "+strings.ToLower(scheme)+" credentials")
+			return
+		}
+		if err != nil {
+			log.Error("authenticate error:", err)
+			writeProblem(c, Problem{Status: http.StatusInternalServerError, Code: codeInternal, Detail: "authenticate failed"})
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), p))
+		c.Next()
+	}
+}
+
+func unauthorized(c *gin.Context, detail string) {
+	c.Header("WWW-Authenticate", `Bearer realm="subscriptions", ApiKey realm="subscriptions"`)
+	writeProblem(c, Problem{Status: http.StatusUnauthorized, Code: codeUnauthorized, Detail: detail})
+}
+
+// ResolveTenant определяет организацию запроса и кладёт её в контекст.
+// Организация берётся из claim tenant_id токена; заголовок X-Tenant-ID, если передан,
+// должен совпадать с ней. Токен без tenant_id выбирает организацию заголовком,
+// только если у вызывающего есть разрешение tenants:manage (или аутентификация отключена).
+// Должен стоять после Authenticate.
+func ResolveTenant(tenants *repository.TenantRepo, log *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var header *uuid.UUID
+		if h := c.GetHeader(tenantHeader); h != "" {
+			id, err := uuid.Parse(h)
+			if err != nil {
+				badRequest(c, tenantHeader, "must be a UUID")
+				return
+			}
+			header = &id
+		}
+
+		var tenantID uuid.UUID
+		p, ok := auth.FromContext(c.Request.Context())
+		switch {
+		case ok && p.TenantID != nil:
+			if header != nil && *header != *p.TenantID {
+				writeProblem(c, Problem{
+					Status: http.StatusForbidden,
+					Code:   service.CodeForbidden,
+					Detail: tenantHeader + " does not match the tenant of the token",
+				})
+				return
+			}
+			tenantID = *p.TenantID
+		case ok && !p.Can(auth.PermTenants):
+			writeProblem(c, Problem{
+				Status: http.StatusForbidden,
+				Code:   service.CodeForbidden,
+				Detail: "token is not bound to a tenant: tenant_id claim is required",
+			})
+			return
+		case header != nil:
+			tenantID = *header
+		default:
+			badRequest(c, tenantHeader, "is required")
+			return
+		}
+
+		exists, err := tenants.Exists(c.Request.Context(), tenantID)
+		if err != nil {
+			log.Error("resolve tenant error:", err)
+			writeProblem(c, Problem{Status: http.StatusInternalServerError, Code: codeInternal, Detail: "resolve tenant failed"})
+			return
+		}
+		if !exists {
+			writeProblem(c, Problem{Status: http.StatusForbidden, Code: service.CodeForbidden, Detail: "unknown tenant"})
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithTenant(c.Request.Context(), tenantID))
+		c.Next()
+	}
+}
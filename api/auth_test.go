@@ -0,0 +1,280 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/repository"
+)
+
+var (
+	tenantA = uuid.MustParse("aaaaaaaa-0000-0000-0000-000000000001")
+	tenantB = uuid.MustParse("bbbbbbbb-0000-0000-0000-000000000002")
+)
+
+// tenantRepo возвращает репозиторий организаций без базы: известны только known.
+func tenantRepo(t *testing.T, known ...uuid.UUID) *repository.TenantRepo {
+	t.Helper()
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Callback().Query().After("gorm:query").Register("test:tenants", func(tx *gorm.DB) {
+		n, ok := tx.Statement.Dest.(*int64)
+		if !ok {
+			return
+		}
+		for _, v := range tx.Statement.Vars {
+			for _, id := range known {
+				if v == id {
+					// Count берёт число строк из RowsAffected, если оно не равно 1
+					*n, tx.RowsAffected = 1, 1
+				}
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repository.NewTenantRepo(db)
+}
+
+// withPrincipal кладёт p в контекст запроса, как это делает Authenticate.
+func withPrincipal(p *auth.Principal) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p != nil {
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), p))
+		}
+		c.Next()
+	}
+}
+
+// echoTenant отвечает организацией запроса.
+func echoTenant(c *gin.Context) {
+	id, _ := auth.TenantID(c.Request.Context())
+	c.String(http.StatusOK, id.String())
+}
+
+func problemCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var p Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("response is not a problem: %s", w.Body)
+	}
+	return p.Code
+}
+
+func TestResolveTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := func(tenant *uuid.UUID) *auth.Principal {
+		return &auth.Principal{UserID: uuid.New(), Roles: []auth.Role{auth.RoleAdmin}, TenantID: tenant}
+	}
+	platform := &auth.Principal{UserID: uuid.New(), Roles: []auth.Role{auth.RolePlatformAdmin, auth.RoleAdmin}}
+	unknown := uuid.New()
+
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		header     string
+		wantStatus int
+		wantTenant uuid.UUID
+	}{
+		{"tenant from the token", user(&tenantA), "", http.StatusOK, tenantA},
+		{"matching header", user(&tenantA), tenantA.String(), http.StatusOK, tenantA},
+		{"header of another tenant", user(&tenantA), tenantB.String(), http.StatusForbidden, uuid.Nil},
+		{"token without tenant picks a tenant", user(nil), tenantB.String(), http.StatusForbidden, uuid.Nil},
+		{"token without tenant and header", user(nil), "", http.StatusForbidden, uuid.Nil},
+		{"api key scopes without tenants:manage", &auth.Principal{Scopes: []auth.Permission{auth.PermReadAll}}, tenantB.String(), http.StatusForbidden, uuid.Nil},
+		{"platform admin picks a tenant", platform, tenantB.String(), http.StatusOK, tenantB},
+		{"platform admin without header", platform, "", http.StatusBadRequest, uuid.Nil},
+		{"platform admin, unknown tenant", platform, unknown.String(), http.StatusForbidden, uuid.Nil},
+		{"platform admin bound to a tenant", &auth.Principal{Roles: []auth.Role{auth.RolePlatformAdmin}, TenantID: &tenantA}, tenantB.String(), http.StatusForbidden, uuid.Nil},
+		{"tenant of the token does not exist", user(&unknown), "", http.StatusForbidden, uuid.Nil},
+		{"header is not a UUID", user(&tenantA), "acme", http.StatusBadRequest, uuid.Nil},
+		{"authentication disabled", nil, tenantA.String(), http.StatusOK, tenantA},
+		{"authentication disabled without header", nil, "", http.StatusBadRequest, uuid.Nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/", withPrincipal(tt.principal), ResolveTenant(tenantRepo(t, tenantA, tenantB), logrus.New()), echoTenant)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tenantHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if w.Body.String() != tt.wantTenant.String() {
+					t.Errorf("tenant = %s, want %s", w.Body, tt.wantTenant)
+				}
+				return
+			}
+			if code := problemCode(t, w); tt.wantStatus == http.StatusForbidden && code != "forbidden" {
+				t.Errorf("code = %s, want forbidden", code)
+			}
+		})
+	}
+}
+
+func TestRequire(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		wantStatus int
+	}{
+		{"not authenticated", nil, http.StatusUnauthorized},
+		{"role without permission", &auth.Principal{Roles: []auth.Role{auth.RoleUser}}, http.StatusForbidden},
+		{"read-only role", &auth.Principal{Roles: []auth.Role{auth.RoleSupportReadOnly}}, http.StatusForbidden},
+		{"role with permission", &auth.Principal{Roles: []auth.Role{auth.RoleFinance}}, http.StatusOK},
+		{"unknown role", &auth.Principal{Roles: []auth.Role{"auditor"}}, http.StatusForbidden},
+		{"api key scope", &auth.Principal{Scopes: []auth.Permission{auth.PermAudit}}, http.StatusOK},
+		{"api key without scope", &auth.Principal{Scopes: []auth.Permission{auth.PermRead}}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/audit", withPrincipal(tt.principal), Require(auth.PermAudit), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/audit", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+func TestAuthenticateBearer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const secret = "test-secret"
+	v, err := auth.NewVerifier(auth.Config{HS256Secret: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := uuid.New()
+	token := func(method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+		s, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	valid := jwt.MapClaims{"sub": userID.String(), "exp": time.Now().Add(time.Hour).Unix(), "tenant_id": tenantA.String()}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{"valid token", "Bearer " + token(jwt.SigningMethodHS256, []byte(secret), valid), http.StatusOK},
+		{"scheme is case-insensitive", "bearer " + token(jwt.SigningMethodHS256, []byte(secret), valid), http.StatusOK},
+		{"no credentials", "", http.StatusUnauthorized},
+		{"empty bearer", "Bearer ", http.StatusUnauthorized},
+		{"unsupported scheme", "Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+		{"alg none", "Bearer " + token(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid), http.StatusUnauthorized},
+		{"missing exp", "Bearer " + token(jwt.SigningMethodHS256, []byte(secret), jwt.MapClaims{"sub": userID.String()}), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/", Authenticate(v, nil, logrus.New()), func(c *gin.Context) {
+				p, _ := auth.FromContext(c.Request.Context())
+				c.String(http.StatusOK, p.UserID.String())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+			if w.Code == http.StatusOK && w.Body.String() != userID.String() {
+				t.Errorf("principal = %s, want %s", w.Body, userID)
+			}
+		})
+	}
+}
+
+// Полная цепочка: токен одной организации не даёт доступа к другой.
+func TestCrossTenantRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const secret = "test-secret"
+	v, err := auth.NewVerifier(auth.Config{HS256Secret: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(claims jwt.MapClaims) string {
+		claims["sub"] = uuid.NewString()
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	r := gin.New()
+	secured := r.Group("/", Authenticate(v, nil, logrus.New()), ResolveTenant(tenantRepo(t, tenantA, tenantB), logrus.New()))
+	secured.GET("/audit", Require(auth.PermAudit), echoTenant)
+
+	tests := []struct {
+		name       string
+		claims     jwt.MapClaims
+		header     string
+		wantStatus int
+	}{
+		{"own tenant", jwt.MapClaims{"tenant_id": tenantA.String(), "roles": []string{"admin"}}, "", http.StatusOK},
+		{"other tenant by header", jwt.MapClaims{"tenant_id": tenantA.String(), "roles": []string{"admin"}}, tenantB.String(), http.StatusForbidden},
+		{"no tenant claim", jwt.MapClaims{"roles": []string{"admin"}}, tenantB.String(), http.StatusForbidden},
+		{"platform admin", jwt.MapClaims{"roles": []string{"platform-admin", "finance"}}, tenantB.String(), http.StatusOK},
+		{"platform admin without audit", jwt.MapClaims{"roles": []string{"platform-admin"}}, tenantB.String(), http.StatusForbidden},
+		{"user without audit", jwt.MapClaims{"tenant_id": tenantA.String()}, "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/audit", nil)
+			req.Header.Set("Authorization", "Bearer "+sign(tt.claims))
+			if tt.header != "" {
+				req.Header.Set(tenantHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
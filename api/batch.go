@@ -0,0 +1,175 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"subscriptions-go/model"
+	"subscriptions-go/service"
+)
+
+// Режимы пакета.
+const (
+	batchAtomic  = "atomic"
+	batchPartial = "partial"
+)
+
+type batchReq struct {
+	Mode       string       `json:"mode,omitempty" binding:"omitempty,oneof=atomic partial"` // по умолчанию atomic
+	Operations []batchOpReq `json:"operations" binding:"required,min=1,dive"`
+}
+
+type batchOpReq struct {
+	Op           string     `json:"op" binding:"required,oneof=create update delete"`
+	ID           string     `json:"id,omitempty" binding:"required_unless=Op create"`           // для update и delete
+	Version      *int64     `json:"version,omitempty" binding:"required_unless=Op create"`      // версия из ETag для update и delete
+	Subscription *createReq `json:"subscription,omitempty" binding:"required_unless=Op delete"` // для create и update
+}
+
+// batchItem — результат одной операции пакета.
+type batchItem struct {
+	Index        int                 `json:"index"`
+	Status       int                 `json:"status"` // статус, который вернул бы одиночный запрос
+	Subscription *model.Subscription `json:"subscription,omitempty"`
+	Error        *Problem            `json:"error,omitempty"`
+}
+
+type batchResp struct {
+	Results []batchItem `json:"results"`
+}
+
+// Action обрабатывает действия над коллекцией вида POST /subscriptions:<действие>.
+func (h *Handler) Action(c *gin.Context) {
+	switch strings.TrimPrefix(c.Param("action"), ":") {
+	case "batch":
+		h.Batch(c)
+	default:
+		writeProblem(c, Problem{Status: http.StatusNotFound, Code: service.CodeNotFound, Detail: "unknown action"})
+	}
+}
+
+// @Summary      Create, update and delete subscriptions in bulk
+// @Description  Выполняет до 100 операций по порядку. В режиме atomic операции выполняются в одной транзакции и первая ошибка отменяет пакет; в режиме partial у каждой операции свой результат. Пересечения проверяются с базой и между операциями пакета
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        X-Tenant-ID      header  string    false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string    false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        batch            body    batchReq  true   "Operations"
+// @Success      200  {object}  batchResp
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      412  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /subscriptions:batch [post]
+func (h *Handler) Batch(c *gin.Context) {
+	var r batchReq
+	if err := c.ShouldBindJSON(&r); err != nil {
+		bindError(c, err)
+		return
+	}
+	atomic := r.Mode == "" || r.Mode == batchAtomic
+
+	items := make([]batchItem, len(r.Operations))
+	ops := make([]service.BatchOp, 0, len(r.Operations))
+	index := make([]int, 0, len(r.Operations)) // номер операции в запросе для каждой из ops
+	for i, o := range r.Operations {
+		items[i].Index = i
+		op, err := o.batchOp()
+		if err != nil {
+			if atomic {
+				writeProblem(c, h.batchProblem(i, err))
+				return
+			}
+			p := h.batchProblem(i, err)
+			items[i].Status, items[i].Error = p.Status, &p
+			continue
+		}
+		ops = append(ops, op)
+		index = append(index, i)
+	}
+
+	if len(ops) > 0 {
+		results, err := h.svc.Batch(c.Request.Context(), ops, atomic)
+		var berr *service.BatchError
+		if errors.As(err, &berr) {
+			writeProblem(c, h.batchProblem(index[berr.Index], berr.Err))
+			return
+		}
+		if err != nil {
+			h.fail(c, "batch", err)
+			return
+		}
+
+		for j, res := range results {
+			item := &items[index[j]]
+			if res.Err != nil {
+				p := h.batchProblem(item.Index, res.Err)
+				item.Status, item.Error = p.Status, &p
+				continue
+			}
+			item.Subscription = res.Subscription
+			switch ops[j].Op {
+			case service.BatchCreate:
+				item.Status = http.StatusCreated
+			case service.BatchUpdate:
+				item.Status = http.StatusOK
+			default:
+				item.Status = http.StatusNoContent
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, batchResp{Results: items})
+}
+
+// batchOp разбирает операцию запроса.
+func (o *batchOpReq) batchOp() (service.BatchOp, error) {
+	op := service.BatchOp{Op: o.Op, Version: o.Version}
+	if o.Op != service.BatchCreate {
+		id, err := uuid.Parse(o.ID)
+		if err != nil {
+			verr := &service.ValidationError{}
+			verr.Add("id", "must be a UUID")
+			return op, verr
+		}
+		op.ID = id
+	}
+	if o.Op != service.BatchDelete {
+		sub, err := o.Subscription.subscription()
+		if err != nil {
+			return op, err
+		}
+		op.Subscription = sub
+	}
+	return op, nil
+}
+
+// batchProblem строит проблему для ошибки операции i с путями полей от корня запроса:
+// поля подписки сервис называет без префикса subscription.
+func (h *Handler) batchProblem(i int, err error) Problem {
+	p := problemFor(h.log, "batch", err)
+	prefix := fmt.Sprintf("operations[%d]", i)
+	p.Detail = prefix + ": " + p.Detail
+	fields := make([]service.FieldError, len(p.Errors))
+	for k, f := range p.Errors {
+		path := prefix + ".subscription." + f.Field
+		switch f.Field {
+		case "op", "id", "version", "subscription":
+			path = prefix + "." + f.Field
+		}
+		fields[k] = service.FieldError{Field: path, Message: f.Message}
+	}
+	p.Errors = fields
+	return p
+}
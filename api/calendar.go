@@ -0,0 +1,188 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"subscriptions-go/ical"
+	"subscriptions-go/model"
+	"subscriptions-go/money"
+	"subscriptions-go/service"
+)
+
+// calendarProdID — идентификатор приложения в лентах календаря.
+const calendarProdID = "-//subscriptions-go//Renewals//EN"
+
+type CalendarHandler struct {
+	svc *service.CalendarService
+	log *logrus.Logger
+}
+
+func NewCalendarHandler(svc *service.CalendarService, log *logrus.Logger) *CalendarHandler {
+	return &CalendarHandler{svc: svc, log: log}
+}
+
+// calendarTokenResp — выпущенный токен ленты и готовый адрес для подписки в календаре.
+type calendarTokenResp struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// @Summary      Issue a calendar feed token
+// @Description  Выпускает токен ленты продлений пользователя; прежний токен перестаёт действовать. Токен возвращается только в этом ответе
+// @Tags         calendar
+// @Produce      json
+// @Param        X-Tenant-ID      header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        id               path    string  true   "User ID"
+// @Success      201  {object}  calendarTokenResp
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /users/{id}/calendar-token [post]
+func (h *CalendarHandler) IssueToken(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+
+	token, err := h.svc.IssueToken(c.Request.Context(), userID)
+	if err != nil {
+		fail(c, h.log, "issue calendar token", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, calendarTokenResp{Token: token, URL: feedURL(c, userID, token)})
+}
+
+// @Summary      Revoke a calendar feed token
+// @Description  Отзывает токен ленты продлений пользователя
+// @Tags         calendar
+// @Produce      json
+// @Param        X-Tenant-ID      header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        id               path    string  true   "User ID"
+// @Success      204
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /users/{id}/calendar-token [delete]
+func (h *CalendarHandler) RevokeToken(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+
+	if err := h.svc.RevokeToken(c.Request.Context(), userID); err != nil {
+		fail(c, h.log, "revoke calendar token", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary      Renewals calendar feed
+// @Description  Лента iCalendar (RFC 5545) с повторяющимся событием на каждую действующую подписку пользователя: даты списаний, цена и запланированные изменения цены. Доступна по токену ленты без заголовка Authorization, чтобы её можно было добавить в календарь по ссылке
+// @Tags         calendar
+// @Produce      text/calendar
+// @Param        id     path   string  true  "User ID"
+// @Param        token  query  string  true  "Calendar feed token"
+// @Success      200  {string}  string
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /users/{id}/renewals.ics [get]
+func (h *CalendarHandler) Feed(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+
+	ctx, err := h.svc.Authenticate(c.Request.Context(), userID, c.Query("token"))
+	if err != nil {
+		fail(c, h.log, "calendar feed", err)
+		return
+	}
+	renewals, err := h.svc.Renewals(ctx, userID)
+	if err != nil {
+		fail(c, h.log, "calendar feed", err)
+		return
+	}
+
+	cal := &ical.Calendar{ProdID: calendarProdID, Name: "Subscription renewals"}
+	for _, r := range renewals {
+		cal.Events = append(cal.Events, renewalEvent(r))
+	}
+
+	c.Header("Content-Type", ical.ContentType)
+	c.Header("Content-Disposition", `inline; filename="renewals.ics"`)
+	// в ссылке на ленту секрет: ответ не должен оседать в общих кэшах
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Status(http.StatusOK)
+	if err := cal.Write(c.Writer, time.Now()); err != nil {
+		h.log.Error("calendar feed error:", err)
+		c.Abort()
+	}
+}
+
+func renewalEvent(r service.Renewal) ical.Event {
+	sub := r.Subscription
+	e := ical.Event{
+		UID:      sub.ID.String() + "@subscriptions-go",
+		Sequence: sub.Version,
+		Start:    r.First,
+		Summary:  sub.ServiceName + " renewal",
+		Recurrence: &ical.Recurrence{
+			Freq:     recurrenceFreq(sub.BillingUnit),
+			Interval: sub.BillingCount,
+			Until:    r.Until,
+		},
+		Except: r.Except,
+	}
+
+	lines := []string{"Price: " + money.Format(r.Price, sub.Currency)}
+	for _, p := range r.PriceChanges {
+		lines = append(lines, fmt.Sprintf("From %s: %s", p.EffectiveFrom.Format("01-2006"), money.Format(p.Price, sub.Currency)))
+	}
+	e.Description = strings.Join(lines, "\n")
+	return e
+}
+
+func recurrenceFreq(unit string) string {
+	switch unit {
+	case model.BillingWeek:
+		return ical.Weekly
+	case model.BillingYear:
+		return ical.Yearly
+	default:
+		return ical.Monthly
+	}
+}
+
+// feedURL строит адрес ленты, по которому клиент календаря сможет её запросить.
+func feedURL(c *gin.Context, userID uuid.UUID, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if p := c.GetHeader("X-Forwarded-Proto"); p == "http" || p == "https" {
+		scheme = p
+	}
+	return fmt.Sprintf("%s://%s/users/%s/renewals.ics?token=%s", scheme, c.Request.Host, userID, token)
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"subscriptions-go/service"
+	"subscriptions-go/statement"
+)
+
+type CandidateHandler struct {
+	svc *service.CandidateService
+	log *logrus.Logger
+}
+
+func NewCandidateHandler(svc *service.CandidateService, log *logrus.Logger) *CandidateHandler {
+	return &CandidateHandler{svc: svc, log: log}
+}
+
+// @Summary      Import a bank statement
+// @Description  Загружает банковскую выписку (CSV, OFX или ISO 20022 camt.053) в теле запроса или файлом file в multipart/form-data, находит регулярные списания (раз в неделю, месяц, квартал или год примерно одной суммой) и предлагает их кандидатами в подписки. Списания сервисов, на которые подписка уже есть, и кандидаты, по которым уже принято решение, не предлагаются. Формат берётся из параметра format, Content-Type или расширения файла. Столбцы CSV по умолчанию: date, amount (списания отрицательные), currency, merchant; другие имена задаются параметрами columns[поле]=столбец
+// @Tags         subscription-candidates
+// @Accept       text/csv
+// @Accept       application/x-ofx
+// @Accept       application/xml
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        X-Tenant-ID      header    string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header    string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        format           query     string  false  "Statement format: csv, ofx or camt053"
+// @Param        user_id          query     string  false  "Account owner, defaults to the caller"
+// @Param        currency         query     string  false  "Currency of CSV rows without a currency column, defaults to the service default"
+// @Param        delimiter        query     string  false  "CSV field delimiter, defaults to a comma"
+// @Param        date_format      query     string  false  "CSV date format: YYYY-MM-DD, DD.MM.YYYY, DD/MM/YYYY, MM/DD/YYYY, DD-MM-YYYY or YYYYMMDD; detected when omitted"
+// @Param        positive_debits  query     bool    false  "CSV amounts of charges are positive"
+// @Param        columns          query     object  false  "CSV column mapping, e.g. columns[merchant]=Description"
+// @Param        file             formData  file    false  "Statement file for multipart/form-data"
+// @Success      200  {object}  service.StatementReport
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      413  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /statements/import [post]
+func (h *CandidateHandler) ImportStatement(c *gin.Context) {
+	in := service.StatementImport{
+		CSV: statement.CSVOptions{
+			Columns:        c.QueryMap("columns"),
+			DateFormat:     c.Query("date_format"),
+			Currency:       c.Query("currency"),
+			PositiveDebits: c.Query("positive_debits") == "true",
+		},
+	}
+	if u := c.Query("user_id"); u != "" {
+		id, err := uuid.Parse(u)
+		if err != nil {
+			badRequest(c, "user_id", "must be a UUID")
+			return
+		}
+		in.UserID = id
+	}
+	comma, ok := queryDelimiter(c)
+	if !ok {
+		return
+	}
+	in.CSV.Comma = comma
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	body, ok := importBody(c)
+	if !ok {
+		return
+	}
+	defer body.Close()
+	in.Body = body
+
+	if in.Format, ok = statementFormat(c); !ok {
+		return
+	}
+
+	report, err := h.svc.ImportStatement(c.Request.Context(), in)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		importReadError(c, err)
+		return
+	}
+	if err != nil {
+		fail(c, h.log, "import statement", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// statementFormat определяет формат выписки по параметру format, а без него — по
+// Content-Type или расширению загруженного файла. Если формат не определён, отвечает
+// 400 и возвращает ok == false.
+func statementFormat(c *gin.Context) (string, bool) {
+	if f := c.Query("format"); f != "" {
+		if !statement.Valid(f) {
+			badRequest(c, "format", "must be one of csv, ofx, camt053")
+			return "", false
+		}
+		return f, true
+	}
+
+	if c.ContentType() != gin.MIMEMultipartPOSTForm {
+		if f := statement.FromMediaType(c.ContentType()); f != "" {
+			return f, true
+		}
+	} else if fh, err := c.FormFile("file"); err == nil {
+		switch strings.ToLower(filepath.Ext(fh.Filename)) {
+		case ".csv":
+			return statement.CSV, true
+		case ".ofx", ".qfx":
+			return statement.OFX, true
+		case ".xml":
+			return statement.CAMT053, true
+		}
+	}
+	badRequest(c, "format", "is required when it cannot be detected from Content-Type or file name")
+	return "", false
+}
+
+// @Summary      List subscription candidates
+// @Description  Кандидаты в подписки, найденные в банковских выписках, новые первыми
+// @Tags         subscription-candidates
+// @Produce      json
+// @Param        X-Tenant-ID  header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        status       query   string  false  "Filter by status: pending, accepted or dismissed"
+// @Param        user_id      query   string  false  "Filter by user ID"
+// @Success      200  {array}   model.SubscriptionCandidate
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /subscription-candidates [get]
+func (h *CandidateHandler) List(c *gin.Context) {
+	var uid *uuid.UUID
+	if u := c.Query("user_id"); u != "" {
+		parsed, err := uuid.Parse(u)
+		if err != nil {
+			badRequest(c, "user_id", "must be a UUID")
+			return
+		}
+		uid = &parsed
+	}
+
+	candidates, err := h.svc.List(c.Request.Context(), uid, c.Query("status"))
+	if err != nil {
+		fail(c, h.log, "list subscription candidates", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, candidates)
+}
+
+// @Summary      Accept a subscription candidate
+// @Description  Создаёт подписку по кандидату и отмечает его принятым
+// @Tags         subscription-candidates
+// @Produce      json
+// @Param        X-Tenant-ID      header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        id               path    string  true   "Candidate ID"
+// @Success      201  {object}  model.Subscription
+// @Header       201  {string}  ETag  "Subscription version"
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /subscription-candidates/{id}/accept [post]
+func (h *CandidateHandler) Accept(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+
+	sub, err := h.svc.Accept(c.Request.Context(), id)
+	if err != nil {
+		fail(c, h.log, "accept subscription candidate", err)
+		return
+	}
+
+	writeSubscription(c, http.StatusCreated, sub)
+}
+
+// @Summary      Dismiss a subscription candidate
+// @Description  Отклоняет кандидата; повторный импорт выписки его больше не предложит
+// @Tags         subscription-candidates
+// @Produce      json
+// @Param        X-Tenant-ID      header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        id               path    string  true   "Candidate ID"
+// @Success      200  {object}  model.SubscriptionCandidate
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /subscription-candidates/{id}/dismiss [post]
+func (h *CandidateHandler) Dismiss(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+
+	candidate, err := h.svc.Dismiss(c.Request.Context(), id)
+	if err != nil {
+		fail(c, h.log, "dismiss subscription candidate", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, candidate)
+}
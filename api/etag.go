@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"subscriptions-go/model"
+)
+
+const codePreconditionRequired = "precondition_required"
+
+// writeSubscription отвечает подпиской с её версией в ETag.
+func writeSubscription(c *gin.Context, status int, sub *model.Subscription) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(sub.Version, 10)))
+	c.JSON(status, sub)
+}
+
+// ifMatch разбирает обязательный заголовок If-Match с версией подписки из ETag.
+// Для If-Match: * версия не проверяется и возвращается nil.
+// Без заголовка отвечает 428, при неверном формате — 400; тогда ok == false.
+func ifMatch(c *gin.Context) (version *int64, ok bool) {
+	h := strings.TrimSpace(c.GetHeader("If-Match"))
+	if h == "" {
+		writeProblem(c, Problem{
+			Status: http.StatusPreconditionRequired,
+			Code:   codePreconditionRequired,
+			Detail: "If-Match header with the subscription ETag is required",
+		})
+		return nil, false
+	}
+	if h == "*" {
+		return nil, true
+	}
+
+	tag, err := strconv.Unquote(strings.TrimPrefix(h, "W/"))
+	if err != nil {
+		badRequest(c, "If-Match", "must be an ETag returned by the API")
+		return nil, false
+	}
+	v, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil {
+		badRequest(c, "If-Match", "must be an ETag returned by the API")
+		return nil, false
+	}
+	return &v, true
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subscriptions-go/export"
+	"subscriptions-go/model"
+	"subscriptions-go/repository"
+	"subscriptions-go/service"
+)
+
+var subscriptionColumns = []string{
+	"id", "service_name", "price", "current_price", "currency", "user_id", "start_date", "end_date",
+	"billing_unit", "billing_count", "trial_end_date", "version", "created_at",
+}
+
+// exportFormat выбирает формат выгрузки по параметру format, а без него — по Accept.
+// Пустая строка означает обычный ответ JSON. При неизвестном format отвечает 400
+// и возвращает ok == false.
+func exportFormat(c *gin.Context) (format string, ok bool) {
+	if f := c.Query("format"); f != "" {
+		if f == "json" {
+			return "", true
+		}
+		if !export.Valid(f) {
+			badRequest(c, "format", "must be one of json, csv, xlsx, ndjson")
+			return "", false
+		}
+		return f, true
+	}
+
+	for _, part := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if mediaType == "application/json" {
+			return "", true
+		}
+		if f := export.FromMediaType(mediaType); f != "" {
+			return f, true
+		}
+	}
+	return "", true
+}
+
+// startExport отвечает 200 с заголовками выгрузки name и создаёт Writer для тела ответа.
+func startExport(c *gin.Context, format, name string, columns []string) (export.Writer, error) {
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	c.Status(http.StatusOK)
+	return export.NewWriter(format, c.Writer, columns)
+}
+
+// exportList выгружает все подписки по фильтру f, читая их страницами по MaxPageSize.
+// Параметры пагинации запроса не учитываются.
+func (h *Handler) exportList(c *gin.Context, f repository.ListFilter, format string) {
+	ctx := c.Request.Context()
+	f.Limit, f.Cursor = repository.MaxPageSize, ""
+
+	// первая страница читается до ответа, чтобы ошибку можно было вернуть статусом
+	page, err := h.svc.Search(ctx, f)
+	if err != nil {
+		h.fail(c, "export", err)
+		return
+	}
+
+	name := "subscriptions"
+	if f.Deleted {
+		name = "deleted-subscriptions"
+	}
+	w, err := startExport(c, format, name, subscriptionColumns)
+	for err == nil {
+		for _, sub := range page.Items {
+			if err = w.WriteRow(subscriptionRow(sub)); err != nil {
+				break
+			}
+		}
+		if err != nil || page.NextCursor == "" {
+			break
+		}
+		if err = w.Flush(); err != nil {
+			break
+		}
+		c.Writer.Flush()
+
+		f.Cursor = page.NextCursor
+		page, err = h.svc.Search(ctx, f)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		// статус уже отправлен: остаётся оборвать выгрузку, клиент получит неполный файл
+		h.log.Error("export error:", err)
+		c.Abort()
+	}
+}
+
+func subscriptionRow(sub *model.Subscription) []any {
+	return []any{
+		sub.ID.String(), sub.ServiceName, sub.Price, sub.CurrentPrice, sub.Currency, sub.UserID.String(),
+		exportMonth(&sub.StartDate), exportMonth(sub.EndDate),
+		sub.BillingUnit, sub.BillingCount, exportMonth(sub.TrialEndDate),
+		sub.Version, sub.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// exportMonth форматирует месяц как в API (MM-YYYY); nil остаётся пустой ячейкой.
+func exportMonth(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.Format("01-2006")
+}
+
+// exportSummary выгружает сводку плоской таблицей: строка на каждую группу
+// нижнего уровня со значениями всех полей группировки и её итогом.
+func (h *Handler) exportSummary(c *gin.Context, summary *service.Summary, format string) {
+	columns := append(append([]string{}, summary.GroupBy...), "currency", "total")
+	w, err := startExport(c, format, "summary", columns)
+	if err == nil {
+		if len(summary.GroupBy) == 0 {
+			err = w.WriteRow([]any{summary.Currency, summary.Total})
+		} else {
+			err = writeSummaryGroups(w, summary.Groups, nil, summary.Currency)
+		}
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		h.log.Error("export summary error:", err)
+		c.Abort()
+	}
+}
+
+func writeSummaryGroups(w export.Writer, groups []service.SummaryGroup, path []any, currency string) error {
+	for _, g := range groups {
+		row := append(path[:len(path):len(path)], g.Value)
+		var err error
+		if len(g.Groups) == 0 {
+			err = w.WriteRow(append(row, currency, g.Total))
+		} else {
+			err = writeSummaryGroups(w, g.Groups, row, currency)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
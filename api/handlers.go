@@ -1,14 +1,22 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 
+	"subscriptions-go/auth"
 	"subscriptions-go/model"
+	"subscriptions-go/money"
+	"subscriptions-go/repository"
 	"subscriptions-go/service"
 )
 
@@ -22,11 +30,53 @@ func NewHandler(svc *service.SubscriptionService, log *logrus.Logger) *Handler {
 }
 
 type createReq struct {
-	ServiceName string  `json:"service_name" binding:"required"`
-	Price       int     `json:"price" binding:"gte=0"`
-	UserID      string  `json:"user_id" binding:"required,uuid"`
-	StartDate   string  `json:"start_date" binding:"required"` // MM-YYYY
-	EndDate     *string `json:"end_date,omitempty"`            // MM-YYYY
+	ServiceName  string  `json:"service_name" binding:"required"`
+	Price        int64   `json:"price" binding:"gte=0"`                                            // в минимальных единицах валюты
+	Currency     string  `json:"currency,omitempty" binding:"omitempty,len=3"`                     // ISO 4217, по умолчанию DEFAULT_CURRENCY
+	UserID       string  `json:"user_id,omitempty" binding:"omitempty,uuid"`                       // по умолчанию вызывающий
+	StartDate    string  `json:"start_date" binding:"required"`                                    // MM-YYYY
+	EndDate      *string `json:"end_date,omitempty"`                                               // MM-YYYY
+	BillingUnit  string  `json:"billing_unit,omitempty" binding:"omitempty,oneof=week month year"` // по умолчанию month
+	BillingCount int     `json:"billing_count,omitempty" binding:"omitempty,gte=1"`                // раз в N единиц, по умолчанию 1
+	TrialMonths  int     `json:"trial_months,omitempty" binding:"omitempty,gte=0"`                 // бесплатные месяцы с начала подписки
+}
+
+// subscription строит подписку из запроса, разбирая даты в формате MM-YYYY.
+func (r *createReq) subscription() (*model.Subscription, error) {
+	verr := &service.ValidationError{}
+
+	var uid uuid.UUID
+	if r.UserID != "" {
+		uid, _ = uuid.Parse(r.UserID)
+	}
+	sd, err := parseMonthYear(r.StartDate)
+	if err != nil {
+		verr.Add("start_date", "must be in MM-YYYY format")
+	}
+
+	var ed *time.Time
+	if r.EndDate != nil {
+		t, err := parseMonthYear(*r.EndDate)
+		if err != nil {
+			verr.Add("end_date", "must be in MM-YYYY format")
+		}
+		ed = &t
+	} // если r.EndDate == nil, ed останется nil — это вечная подписка
+
+	if err := verr.OrNil(); err != nil {
+		return nil, err
+	}
+	return &model.Subscription{
+		ServiceName:  r.ServiceName,
+		Price:        r.Price,
+		Currency:     r.Currency,
+		UserID:       uid,
+		StartDate:    sd,
+		EndDate:      ed,
+		BillingUnit:  r.BillingUnit,
+		BillingCount: r.BillingCount,
+		TrialEndDate: trialEnd(sd, r.TrialMonths),
+	}, nil
 }
 
 // @Summary      Create a subscription
@@ -34,166 +84,311 @@ type createReq struct {
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
+// @Param        X-Tenant-ID   header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
 // @Param        subscription  body  createReq  true  "Subscription info"
 // @Success      201  {object}  model.Subscription
-// @Failure      400  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
 // @Router       /subscriptions [post]
 func (h *Handler) Create(c *gin.Context) {
 	var r createReq
 	if err := c.ShouldBindJSON(&r); err != nil {
-		h.log.Warn("bad request create:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindError(c, err)
 		return
 	}
 
-	uid, _ := uuid.Parse(r.UserID)
-	sd, err := parseMonthYear(r.StartDate)
+	sub, err := r.subscription()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date must be in MM-YYYY format"})
+		h.fail(c, "create", err)
 		return
 	}
 
-	var ed *time.Time
-	if r.EndDate != nil {
-		t, err := parseMonthYear(*r.EndDate)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must be in MM-YYYY format"})
-			return
-		}
-		ed = &t
-	} // если r.EndDate == nil, ed останется nil — это вечная подписка
-
-	sub := &model.Subscription{
-		ServiceName: r.ServiceName,
-		Price:       r.Price,
-		UserID:      uid,
-		StartDate:   sd,
-		EndDate:     ed,
-	}
-
-	if err := h.svc.Create(sub); err != nil {
-		h.log.Error("create error:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create"})
+	if err := h.svc.Create(c.Request.Context(), sub); err != nil {
+		h.fail(c, "create", err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, sub)
+	writeSubscription(c, http.StatusCreated, sub)
 }
 
 // @Summary      Get a subscription by ID
-// @Description  Получить подписку по ID
+// @Description  Получить подписку по ID; ETag ответа передаётся в If-Match при изменении и удалении
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
+// @Param        X-Tenant-ID   header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
 // @Param        id   path      string  true  "Subscription ID"
 // @Success      200  {object}  model.Subscription
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
+// @Header       200  {string}  ETag  "Subscription version"
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Security     BearerAuth
 // @Router       /subscriptions/{id} [get]
 func (h *Handler) Get(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		badRequest(c, "id", "must be a UUID")
 		return
 	}
 
-	sub, err := h.svc.GetByID(id)
+	sub, err := h.svc.GetByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+		h.fail(c, "get", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, sub)
+	writeSubscription(c, http.StatusOK, sub)
 }
 
 // @Summary      List subscriptions
-// @Description  Список всех подписок
+// @Description  Список подписок с фильтрами, сортировкой и постраничной выдачей по курсору
 // @Tags         subscriptions
 // @Accept       json
-// @Produce      json
-// @Param        user_id       query   string  false "Filter by user ID"
-// @Param        service_name  query   string  false "Filter by service name"
-// @Success      200  {array}   model.Subscription
-// @Failure      400  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Produce      json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/x-ndjson
+// @Param        X-Tenant-ID   header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        user_id         query   string  false "Filter by user ID"
+// @Param        service_name    query   string  false "Filter by exact service name"
+// @Param        service_prefix  query   string  false "Filter by service name prefix (case-insensitive)"
+// @Param        price_min       query   int     false "Minimum current price (minor units)"
+// @Param        price_max       query   int     false "Maximum current price (minor units)"
+// @Param        active_at       query   string  false "Active in month MM-YYYY"
+// @Param        start_from      query   string  false "Start month from MM-YYYY"
+// @Param        start_to        query   string  false "Start month to MM-YYYY"
+// @Param        end_from        query   string  false "End month from MM-YYYY"
+// @Param        end_to          query   string  false "End month to MM-YYYY"
+// @Param        sort            query   string  false "Sort field: start_date, price (current price), created_at, service_name"
+// @Param        order           query   string  false "Sort order: asc or desc"
+// @Param        limit           query   int     false "Page size (default 50, max 500)"
+// @Param        cursor          query   string  false "Cursor from next_cursor of the previous page"
+// @Param        format          query   string  false "Export format: json (default), csv, xlsx or ndjson; the Accept header is used when omitted"
+// @Success      200  {object}  repository.Page
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
 // @Router       /subscriptions [get]
 func (h *Handler) List(c *gin.Context) {
-	var uid *uuid.UUID
+	h.list(c, repository.ListFilter{})
+}
+
+// @Summary      List deleted subscriptions
+// @Description  Удалённые подписки организации, ещё не очищенные окончательно; фильтры и пагинация как у списка подписок
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/x-ndjson
+// @Param        X-Tenant-ID     header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        user_id         query   string  false "Filter by user ID"
+// @Param        service_name    query   string  false "Filter by exact service name"
+// @Param        service_prefix  query   string  false "Filter by service name prefix (case-insensitive)"
+// @Param        sort            query   string  false "Sort field: start_date, price (current price), created_at, service_name"
+// @Param        order           query   string  false "Sort order: asc or desc"
+// @Param        limit           query   int     false "Page size (default 50, max 500)"
+// @Param        cursor          query   string  false "Cursor from next_cursor of the previous page"
+// @Param        format          query   string  false "Export format: json (default), csv, xlsx or ndjson; the Accept header is used when omitted"
+// @Success      200  {object}  repository.Page
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /subscriptions/deleted [get]
+func (h *Handler) ListDeleted(c *gin.Context) {
+	h.list(c, repository.ListFilter{Deleted: true})
+}
+
+func (h *Handler) list(c *gin.Context, f repository.ListFilter) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
 	if u := c.Query("user_id"); u != "" {
 		parsed, err := uuid.Parse(u)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id must be uuid"})
+			badRequest(c, "user_id", "must be a UUID")
 			return
 		}
-		uid = &parsed
+		f.UserID = &parsed
 	}
 
-	var svcName *string
 	if s := c.Query("service_name"); s != "" {
-		svcName = &s
+		f.ServiceName = &s
+	}
+	if s := c.Query("service_prefix"); s != "" {
+		f.ServicePrefix = &s
+	}
+
+	if f.MinPrice, ok = queryInt64(c, "price_min"); !ok {
+		return
+	}
+	if f.MaxPrice, ok = queryInt64(c, "price_max"); !ok {
+		return
+	}
+	if f.ActiveAt, ok = queryMonth(c, "active_at"); !ok {
+		return
+	}
+	if f.StartFrom, ok = queryMonth(c, "start_from"); !ok {
+		return
+	}
+	if f.StartTo, ok = queryMonth(c, "start_to"); !ok {
+		return
+	}
+	if f.EndFrom, ok = queryMonth(c, "end_from"); !ok {
+		return
+	}
+	if f.EndTo, ok = queryMonth(c, "end_to"); !ok {
+		return
+	}
+
+	f.Sort = c.DefaultQuery("sort", "start_date")
+	if !repository.ValidSort(f.Sort) {
+		badRequest(c, "sort", "must be one of start_date, price, created_at, service_name")
+		return
+	}
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		f.Desc = true
+	default:
+		badRequest(c, "order", "must be asc or desc")
+		return
+	}
+
+	if l := c.Query("limit"); l != "" {
+		v, err := strconv.Atoi(l)
+		if err != nil || v < 1 || v > repository.MaxPageSize {
+			badRequest(c, "limit", fmt.Sprintf("must be between 1 and %d", repository.MaxPageSize))
+			return
+		}
+		f.Limit = v
+	}
+	f.Cursor = c.Query("cursor")
+
+	if format != "" {
+		h.exportList(c, f, format)
+		return
 	}
 
-	subs, err := h.svc.List(uid, svcName)
+	page, err := h.svc.Search(c.Request.Context(), f)
 	if err != nil {
-		h.log.Error("list error:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			badRequest(c, "cursor", err.Error())
+			return
+		}
+		h.fail(c, "list", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, subs)
+	c.JSON(http.StatusOK, page)
 }
 
-// @Summary      Get subscription summary
-// @Description  Суммарная информация по подпискам за период
+// @Summary      List converting trials
+// @Description  Подписки, пробный период которых закончится и станет платным в ближайшие N дней
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
-// @Param        start         query   string  true  "Start month MM-YYYY"
-// @Param        end           query   string  true  "End month MM-YYYY"
-// @Param        user_id       query   string  false "Filter by user ID"
-// @Param        service_name  query   string  false "Filter by service name"
-// @Success      200  {object}  map[string]int64
-// @Failure      400  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
-// @Router       /subscriptions/summary [get]
+// @Param        X-Tenant-ID   header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        days     query   int     false "Window in days (default 30)"
+// @Param        user_id  query   string  false "Filter by user ID"
+// @Success      200  {array}   model.Subscription
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /subscriptions/trials [get]
+func (h *Handler) Trials(c *gin.Context) {
+	days := 30
+	if d := c.Query("days"); d != "" {
+		v, err := strconv.Atoi(d)
+		if err != nil || v < 0 {
+			badRequest(c, "days", "must be a non-negative integer")
+			return
+		}
+		days = v
+	}
+
+	var uid *uuid.UUID
+	if u := c.Query("user_id"); u != "" {
+		parsed, err := uuid.Parse(u)
+		if err != nil {
+			badRequest(c, "user_id", "must be a UUID")
+			return
+		}
+		uid = &parsed
+	}
+
+	subs, err := h.svc.TrialsConverting(c.Request.Context(), days, uid)
+	if err != nil {
+		h.fail(c, "trials", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subs)
+}
+
 // @Summary      Get subscription summary
-// @Description  Суммарная стоимость подписок за указанный период с учётом фильтров
+// @Description  Суммарная стоимость подписок за указанный период с учётом фильтров, в выбранной валюте, с разбивкой по валютам и, при group_by, по сервисам, пользователям и месяцам. Без user_id сводка по всем пользователям доступна ролям finance и admin, остальным — по своим подпискам
 // @Tags         subscriptions
 // @Accept       json
-// @Produce      json
+// @Produce      json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/x-ndjson
+// @Param        X-Tenant-ID   header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
 // @Param        start         query   string  true  "Start month MM-YYYY"
 // @Param        end           query   string  true  "End month MM-YYYY"
 // @Param        user_id       query   string  false "Filter by user ID"
 // @Param        service_name  query   string  false "Filter by service name"
-// @Success      200  {object}  map[string]int64
-// @Failure      400  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Param        currency      query   string  false "Report currency (ISO 4217), defaults to DEFAULT_CURRENCY"
+// @Param        group_by      query   string  false "Comma-separated breakdown fields: service_name, user_id, month"
+// @Param        format        query   string  false "Export format: json (default), csv, xlsx or ndjson; the Accept header is used when omitted"
+// @Success      200  {object}  service.Summary
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
 // @Router       /subscriptions/summary [get]
 func (h *Handler) Summary(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
 	startStr := c.Query("start")
 	endStr := c.Query("end")
 
-	if startStr == "" || endStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "start and end required (MM-YYYY)"})
+	if startStr == "" {
+		badRequest(c, "start", "is required (MM-YYYY)")
+		return
+	}
+	if endStr == "" {
+		badRequest(c, "end", "is required (MM-YYYY)")
 		return
 	}
 
 	periodStart, err := parseMonthYear(startStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "start must be MM-YYYY"})
+		badRequest(c, "start", "must be in MM-YYYY format")
 		return
 	}
 
 	periodEnd, err := parseMonthYear(endStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "end must be MM-YYYY"})
+		badRequest(c, "end", "must be in MM-YYYY format")
 		return
 	}
 
 	if periodEnd.Before(periodStart) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "end date cannot be before start date"})
+		badRequest(c, "end", "must not be before start")
 		return
 	}
 
@@ -201,85 +396,105 @@ func (h *Handler) Summary(c *gin.Context) {
 	if u := c.Query("user_id"); u != "" {
 		uid, err := uuid.Parse(u)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id must be valid UUID"})
+			badRequest(c, "user_id", "must be a UUID")
 			return
 		}
 		userID = &uid
 	}
 
+	// сводка по нескольким пользователям доступна только finance и admin,
+	// остальным без user_id считается сводка по своим подпискам
+	if userID == nil && !auth.Can(c.Request.Context(), auth.PermSummaryAll) {
+		p, _ := auth.FromContext(c.Request.Context())
+		userID = &p.UserID
+	}
+
 	var serviceName *string
 	if s := c.Query("service_name"); s != "" {
 		serviceName = &s
 	}
 
-	// Получаем все подходящие подписки
-	subs, err := h.svc.List(userID, serviceName)
-	if err != nil {
-		h.log.Error("summary error:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	currency := money.Normalize(c.Query("currency"))
+	if currency != "" && !money.Valid(currency) {
+		badRequest(c, "currency", "must be a known ISO 4217 code")
 		return
 	}
 
-	var total int64
-	for _, sub := range subs {
-		subStart := sub.StartDate
-		subEnd := sub.EndDate
-
-		// Если подписка "вечная", считаем её до конца периода
-		if subEnd == nil || subEnd.After(periodEnd) {
-			subEnd = &periodEnd
-		}
-
-		// Если подписка начинается после конца периода — пропускаем
-		if subStart.After(periodEnd) {
-			continue
-		}
-		// Если подписка закончилась до начала периода — тоже пропускаем
-		if subEnd.Before(periodStart) {
-			continue
+	var groupBy []string
+	if g := c.Query("group_by"); g != "" {
+		groupBy = strings.Split(g, ",")
+		if err := service.ValidGroupBy(groupBy); err != nil {
+			h.fail(c, "summary", err)
+			return
 		}
+	}
 
-		// Считаем пересечение дат
-		overlapStart := maxTime(subStart, periodStart)
-		overlapEnd := minTime(*subEnd, periodEnd)
-
-		// Подсчёт месяцев пересечения
-		months := monthsBetween(overlapStart, overlapEnd)
-		if months > 0 {
-			total += int64(months) * int64(sub.Price)
-		}
+	summary, err := h.svc.Summary(c.Request.Context(), service.SummaryQuery{
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		UserID:      userID,
+		ServiceName: serviceName,
+		Currency:    currency,
+		GroupBy:     groupBy,
+	})
+	if err != nil {
+		h.fail(c, "summary", err)
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"total_rub": total})
+	if format != "" {
+		h.exportSummary(c, summary, format)
+		return
+	}
+	c.JSON(http.StatusOK, summary)
 }
 
 // --- Вспомогательные функции ---
-func maxTime(a, b time.Time) time.Time {
-	if a.After(b) {
-		return a
+func parseMonthYear(mmYYYY string) (time.Time, error) {
+	t, err := time.Parse("01-2006", mmYYYY)
+	if err != nil {
+		return time.Time{}, err
 	}
-	return b
+	return t, nil
 }
 
-func minTime(a, b time.Time) time.Time {
-	if a.Before(b) {
-		return a
+// queryMonth разбирает необязательный параметр запроса в формате MM-YYYY.
+// При ошибке отвечает 400 и возвращает ok == false.
+func queryMonth(c *gin.Context, name string) (*time.Time, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, true
+	}
+	t, err := parseMonthYear(v)
+	if err != nil {
+		badRequest(c, name, "must be in MM-YYYY format")
+		return nil, false
 	}
-	return b
+	return &t, true
 }
 
-func monthsBetween(start, end time.Time) int {
-	yearDiff := end.Year() - start.Year()
-	monthDiff := int(end.Month()) - int(start.Month())
-	return yearDiff*12 + monthDiff
+// queryInt64 разбирает необязательный целочисленный параметр запроса.
+// При ошибке отвечает 400 и возвращает ok == false.
+func queryInt64(c *gin.Context, name string) (*int64, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, true
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		badRequest(c, name, "must be an integer")
+		return nil, false
+	}
+	return &n, true
 }
 
-func parseMonthYear(mmYYYY string) (time.Time, error) {
-	t, err := time.Parse("01-2006", mmYYYY)
-	if err != nil {
-		return time.Time{}, err
+// trialEnd возвращает первый платный месяц для пробного периода длиной months месяцев.
+func trialEnd(start time.Time, months int) *time.Time {
+	if months <= 0 {
+		return nil
 	}
-	return t, nil
+	t := start.AddDate(0, months, 0)
+	return &t
 }
 
 // @Summary      Update a subscription
@@ -287,30 +502,46 @@ func parseMonthYear(mmYYYY string) (time.Time, error) {
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
+// @Param        X-Tenant-ID   header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        If-Match      header  string  true   "ETag of the subscription from GET, or *"
 // @Param        id            path      string         true  "Subscription ID"
 // @Param        subscription  body      createReq      true  "Subscription info"
 // @Success      200  {object}  model.Subscription
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Header       200  {string}  ETag  "Subscription version"
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      412  {object}  Problem
+// @Failure      428  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
 // @Router       /subscriptions/{id} [put]
 func (h *Handler) Update(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
 		return
 	}
 
 	var r createReq
 	if err := c.ShouldBindJSON(&r); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindError(c, err)
 		return
 	}
 
 	sd, err := parseMonthYear(r.StartDate)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date must be MM-YYYY"})
+		badRequest(c, "start_date", "must be in MM-YYYY format")
 		return
 	}
 
@@ -318,55 +549,249 @@ func (h *Handler) Update(c *gin.Context) {
 	if r.EndDate != nil {
 		t, err := parseMonthYear(*r.EndDate)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must be MM-YYYY"})
+			badRequest(c, "end_date", "must be in MM-YYYY format")
 			return
 		}
 		ed = &t
 	}
 
-	sub, err := h.svc.GetByID(id)
+	sub, err := h.svc.GetByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+		h.fail(c, "update", err)
 		return
 	}
 
 	sub.ServiceName = r.ServiceName
 	sub.Price = r.Price
-	sub.UserID, _ = uuid.Parse(r.UserID)
+	sub.Currency = r.Currency
+	if r.UserID != "" {
+		sub.UserID, _ = uuid.Parse(r.UserID)
+	}
 	sub.StartDate = sd
 	sub.EndDate = ed
+	sub.BillingUnit = r.BillingUnit
+	sub.BillingCount = r.BillingCount
+	sub.TrialEndDate = trialEnd(sd, r.TrialMonths)
+	if version != nil {
+		sub.Version = *version
+	}
 
-	if err := h.svc.Update(sub); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update"})
+	if err := h.svc.Update(c.Request.Context(), sub); err != nil {
+		h.fail(c, "update", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, sub)
+	writeSubscription(c, http.StatusOK, sub)
 }
 
 // @Summary      Delete a subscription
-// @Description  Удаляет подписку по ID
+// @Description  Удаляет подписку по ID; до окончательной очистки её можно восстановить
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
+// @Param        X-Tenant-ID   header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        If-Match      header  string  true   "ETag of the subscription from GET, or *"
 // @Param        id   path      string  true  "Subscription ID"
 // @Success      204
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      412  {object}  Problem
+// @Failure      428  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
 // @Router       /subscriptions/{id} [delete]
 func (h *Handler) Delete(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		badRequest(c, "id", "must be a UUID")
 		return
 	}
 
-	if err := h.svc.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Delete(c.Request.Context(), id, version); err != nil {
+		h.fail(c, "delete", err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+// @Summary      Restore a deleted subscription
+// @Description  Восстанавливает удалённую подписку, если её период не пересекается с другими подписками
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        X-Tenant-ID   header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        id   path      string  true  "Subscription ID"
+// @Success      200  {object}  model.Subscription
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /subscriptions/{id}/restore [post]
+func (h *Handler) Restore(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+
+	sub, err := h.svc.Restore(c.Request.Context(), id)
+	if err != nil {
+		h.fail(c, "restore", err)
+		return
+	}
+
+	writeSubscription(c, http.StatusOK, sub)
+}
+
+type priceChangeReq struct {
+	Price         int64  `json:"price" binding:"gte=0"`             // в минимальных единицах валюты подписки
+	EffectiveFrom string `json:"effective_from" binding:"required"` // MM-YYYY
+}
+
+// @Summary      Schedule a price change
+// @Description  Задаёт новую цену подписки начиная с указанного месяца; прошлые месяцы сохраняют прежнюю цену
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        X-Tenant-ID   header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        id      path      string          true  "Subscription ID"
+// @Param        change  body      priceChangeReq  true  "Price change"
+// @Success      200  {object}  model.Subscription
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /subscriptions/{id}/prices [post]
+func (h *Handler) SchedulePriceChange(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+
+	var r priceChangeReq
+	if err := c.ShouldBindJSON(&r); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	from, err := parseMonthYear(r.EffectiveFrom)
+	if err != nil {
+		badRequest(c, "effective_from", "must be in MM-YYYY format")
+		return
+	}
+
+	sub, err := h.svc.SchedulePriceChange(c.Request.Context(), id, r.Price, from)
+	if err != nil {
+		h.fail(c, "price change", err)
+		return
+	}
+
+	writeSubscription(c, http.StatusOK, sub)
+}
+
+type pauseReq struct {
+	From string `json:"from,omitempty"` // MM-YYYY, по умолчанию текущий месяц
+}
+
+// @Summary      Pause a subscription
+// @Description  Приостанавливает подписку с указанного месяца; месяцы паузы не учитываются в сумме
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        X-Tenant-ID   header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        id     path      string    true   "Subscription ID"
+// @Param        pause  body      pauseReq  false  "Pause start"
+// @Success      200  {object}  model.Subscription
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /subscriptions/{id}/pause [post]
+func (h *Handler) Pause(c *gin.Context) {
+	h.pauseAction(c, h.svc.Pause)
+}
+
+// @Summary      Resume a subscription
+// @Description  Возобновляет приостановленную подписку с указанного месяца
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        X-Tenant-ID   header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        id      path      string    true   "Subscription ID"
+// @Param        resume  body      pauseReq  false  "First billed month after the pause"
+// @Success      200  {object}  model.Subscription
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /subscriptions/{id}/resume [post]
+func (h *Handler) Resume(c *gin.Context) {
+	h.pauseAction(c, h.svc.Resume)
+}
+
+func (h *Handler) pauseAction(c *gin.Context, action func(context.Context, uuid.UUID, time.Time) (*model.Subscription, error)) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+
+	var r pauseReq
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&r); err != nil {
+			bindError(c, err)
+			return
+		}
+	}
+
+	from := time.Now().UTC()
+	if r.From != "" {
+		from, err = parseMonthYear(r.From)
+		if err != nil {
+			badRequest(c, "from", "must be in MM-YYYY format")
+			return
+		}
+	}
+
+	sub, err := action(c.Request.Context(), id, from)
+	if err != nil {
+		h.fail(c, "pause", err)
+		return
+	}
+
+	writeSubscription(c, http.StatusOK, sub)
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/model"
+	"subscriptions-go/repository"
+)
+
+const (
+	idempotencyHeader       = "Idempotency-Key"
+	idempotencyReplayHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize ограничивает тело, которое читается в память ради отпечатка
+	// запроса; больше всего весят загружаемые файлы импорта
+	maxIdempotentBodySize = maxImportSize
+
+	codeIdempotencyKeyReused  = "idempotency_key_reused"
+	codeIdempotencyInProgress = "idempotency_in_progress"
+)
+
+// replayedHeaders — заголовки ответа, которые сохраняются вместе с телом.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// Idempotency обрабатывает заголовок Idempotency-Key в изменяющих запросах.
+// Первый запрос с ключом выполняется, его ответ хранится ttl; повтор с тем же
+// ключом получает сохранённый ответ, не выполняясь снова. Повтор с другим методом,
+// путём или телом отклоняется с 422, повтор до завершения первого запроса — с 409.
+// Ответы 5xx не сохраняются: такой запрос можно повторить с тем же ключом.
+func Idempotency(keys *repository.IdempotencyRepo, ttl time.Duration, log *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyHeader)
+		if key == "" || !mutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if !validIdempotencyKey(key) {
+			badRequest(c, idempotencyHeader, "must be 1 to 255 printable ASCII characters")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeProblem(c, Problem{
+				Status: http.StatusRequestEntityTooLarge,
+				Code:   codeInvalidRequest,
+				Detail: fmt.Sprintf("request body must not exceed %d bytes", maxIdempotentBodySize),
+			})
+			return
+		}
+		if err != nil {
+			writeProblem(c, Problem{Status: http.StatusBadRequest, Code: codeInvalidRequest, Detail: "cannot read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		rec := &model.IdempotencyKey{
+			Actor:       auth.Actor(ctx),
+			Key:         key,
+			Fingerprint: fingerprint(c.Request, body),
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+		existing, err := keys.Reserve(ctx, rec)
+		if err != nil {
+			fail(c, log, "idempotency", err)
+			return
+		}
+		if existing != nil {
+			replay(c, rec, existing)
+			return
+		}
+
+		// ответ сохраняется и после отмены запроса клиентом: иначе повтор выполнится дважды
+		ctx = context.WithoutCancel(ctx)
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := keys.Release(ctx, rec); err != nil {
+				log.Error("release idempotency key error:", err)
+			}
+		}()
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		if w.Status() >= http.StatusInternalServerError {
+			return
+		}
+		rec.Status = w.Status()
+		rec.Body = w.body.Bytes()
+		headers := make(map[string]string)
+		for _, h := range replayedHeaders {
+			if v := w.Header().Get(h); v != "" {
+				headers[h] = v
+			}
+		}
+		rec.Headers, _ = json.Marshal(headers)
+		if err := keys.Complete(ctx, rec); err != nil {
+			log.Error("save idempotent response error:", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// replay отвечает на повтор запроса с уже занятым ключом.
+func replay(c *gin.Context, rec, existing *model.IdempotencyKey) {
+	switch {
+	case existing.Fingerprint != rec.Fingerprint:
+		writeProblem(c, Problem{
+			Status: http.StatusUnprocessableEntity,
+			Code:   codeIdempotencyKeyReused,
+			Detail: "Idempotency-Key was already used for a different request",
+		})
+	case existing.Status == 0:
+		writeProblem(c, Problem{
+			Status: http.StatusConflict,
+			Code:   codeIdempotencyInProgress,
+			Detail: "a request with this Idempotency-Key is still in progress",
+		})
+	default:
+		var headers map[string]string
+		_ = json.Unmarshal(existing.Headers, &headers)
+		for k, v := range headers {
+			c.Header(k, v)
+		}
+		c.Header(idempotencyReplayHeader, "true")
+		c.Status(existing.Status)
+		_, _ = c.Writer.Write(existing.Body)
+		c.Abort()
+	}
+}
+
+// fingerprint отличает запросы с одним ключом: учитываются метод, путь с параметрами и тело.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for _, r := range key {
+		if r < 0x20 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// recordingWriter копирует тело ответа, чтобы его можно было сохранить.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
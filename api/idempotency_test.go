@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"subscriptions-go/repository"
+)
+
+func TestIdempotencyRejectsLargeBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	// тело отклоняется до обращения к хранилищу ключей
+	r.Use(Idempotency(repository.NewIdempotencyRepo(nil), time.Hour, logrus.New()))
+	r.POST("/subscriptions/import", func(c *gin.Context) {
+		t.Error("handler must not be called")
+	})
+
+	body := strings.NewReader(strings.Repeat("x", maxIdempotentBodySize+1))
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions/import", body)
+	req.Header.Set(idempotencyHeader, "key-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d: %s", w.Code, http.StatusRequestEntityTooLarge, w.Body)
+	}
+}
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"subscriptions-go/model"
+	"subscriptions-go/service"
+)
+
+// maxImportSize ограничивает размер загружаемого CSV.
+const maxImportSize = 10 << 20
+
+// importColumns — поля, которые можно загрузить из CSV; по умолчанию столбец
+// называется так же, как поле.
+var importColumns = []string{"service_name", "price", "currency", "user_id", "start_date", "end_date"}
+
+// requiredImportColumns должны быть в файле обязательно.
+var requiredImportColumns = map[string]bool{"service_name": true, "price": true, "start_date": true}
+
+// importRow — результат разбора и проверки строки CSV.
+type importRow struct {
+	Line         int                 `json:"line"` // номер строки в файле, заголовок — строка 1
+	Subscription *model.Subscription `json:"subscription,omitempty"`
+	Error        *Problem            `json:"error,omitempty"`
+}
+
+// importReport — отчёт об импорте; при dry_run подписки не записываются.
+type importReport struct {
+	DryRun   bool        `json:"dry_run"`
+	Total    int         `json:"total"`
+	Valid    int         `json:"valid"`
+	Invalid  int         `json:"invalid"`
+	Imported int         `json:"imported"`
+	Rows     []importRow `json:"rows"`
+}
+
+// @Summary      Import subscriptions from CSV
+// @Description  Загружает подписки из CSV (text/csv в теле запроса или файл file в multipart/form-data). Столбцы по умолчанию: service_name, price (в минимальных единицах валюты), currency, user_id, start_date и end_date (MM-YYYY); другие имена задаются параметрами columns[поле]=столбец. При dry_run=true возвращает отчёт по строкам без записи; иначе строки без ошибок импортируются в одной транзакции
+// @Tags         subscriptions
+// @Accept       text/csv
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        X-Tenant-ID      header    string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header    string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        dry_run          query     bool    false  "Only validate and report, do not import"
+// @Param        delimiter        query     string  false  "Field delimiter, defaults to a comma"
+// @Param        columns          query     object  false  "Column mapping, e.g. columns[service_name]=Service"
+// @Param        file             formData  file    false  "CSV file for multipart/form-data"
+// @Success      200  {object}  importReport
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      413  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /subscriptions/import [post]
+func (h *Handler) Import(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+
+	comma, ok := queryDelimiter(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	body, ok := importBody(c)
+	if !ok {
+		return
+	}
+	defer body.Close()
+
+	cr := csv.NewReader(body)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		importReadError(c, err)
+		return
+	}
+	columns, err := importIndex(header, c.QueryMap("columns"))
+	if err != nil {
+		h.fail(c, "import", err)
+		return
+	}
+
+	var rows []importRow
+	var subs []*model.Subscription
+	var index []int // номер строки отчёта для каждой из subs
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			importReadError(c, err)
+			return
+		}
+		line, _ := cr.FieldPos(0)
+		if blankRecord(record) {
+			continue
+		}
+
+		row := importRow{Line: line}
+		sub, err := importSubscription(record, columns)
+		if err != nil {
+			p := problemFor(h.log, "import", err)
+			row.Error = &p
+		} else {
+			subs = append(subs, sub)
+			index = append(index, len(rows))
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		badRequest(c, "file", "must contain at least one row")
+		return
+	}
+	if len(subs) > 0 {
+		results, err := h.svc.Import(c.Request.Context(), subs, dryRun)
+		var berr *service.BatchError
+		if errors.As(err, &berr) {
+			p := problemFor(h.log, "import", berr.Err)
+			p.Detail = fmt.Sprintf("line %d: %s", rows[index[berr.Index]].Line, p.Detail)
+			writeProblem(c, p)
+			return
+		}
+		if err != nil {
+			h.fail(c, "import", err)
+			return
+		}
+
+		for j, res := range results {
+			row := &rows[index[j]]
+			if res.Err != nil {
+				p := problemFor(h.log, "import", res.Err)
+				row.Error = &p
+				continue
+			}
+			row.Subscription = res.Subscription
+		}
+	}
+
+	report := importReport{DryRun: dryRun, Total: len(rows), Rows: rows}
+	for _, row := range rows {
+		if row.Error != nil {
+			report.Invalid++
+		} else {
+			report.Valid++
+		}
+	}
+	if !dryRun {
+		report.Imported = report.Valid
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// importBody возвращает CSV из файла file формы или из тела запроса.
+// При ошибке отвечает 400 и возвращает ok == false.
+func importBody(c *gin.Context) (io.ReadCloser, bool) {
+	if c.ContentType() != gin.MIMEMultipartPOSTForm {
+		return c.Request.Body, true
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		badRequest(c, "file", "is required")
+		return nil, false
+	}
+	f, err := fh.Open()
+	if err != nil {
+		badRequest(c, "file", "cannot be read")
+		return nil, false
+	}
+	return f, true
+}
+
+// queryDelimiter читает разделитель полей CSV из параметра delimiter, по умолчанию запятую.
+// При неверном значении отвечает 400 и возвращает ok == false.
+func queryDelimiter(c *gin.Context) (rune, bool) {
+	d := c.Query("delimiter")
+	if d == "" {
+		return ',', true
+	}
+	r, size := utf8.DecodeRuneInString(d)
+	if size != len(d) || r == '"' || r == '\r' || r == '\n' {
+		badRequest(c, "delimiter", "must be a single character")
+		return 0, false
+	}
+	return r, true
+}
+
+// importReadError отвечает на ошибку чтения CSV: 413 для слишком большого файла, иначе 400.
+func importReadError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeProblem(c, Problem{
+			Status: http.StatusRequestEntityTooLarge,
+			Code:   codeInvalidRequest,
+			Detail: fmt.Sprintf("file must not exceed %d bytes", maxImportSize),
+		})
+		return
+	}
+	if errors.Is(err, io.EOF) {
+		badRequest(c, "file", "must start with a header row")
+		return
+	}
+	writeProblem(c, Problem{Status: http.StatusBadRequest, Code: codeInvalidRequest, Detail: "invalid CSV: " + err.Error()})
+}
+
+// importIndex сопоставляет поля подписки с номерами столбцов по заголовку и mapping.
+func importIndex(header []string, mapping map[string]string) (map[string]int, error) {
+	verr := &service.ValidationError{}
+	for _, field := range sortedKeys(mapping) {
+		if !validImportColumn(field) {
+			verr.Add("columns["+field+"]", "is not an importable field")
+		}
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // BOM из Excel
+		}
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	columns := make(map[string]int)
+	for _, field := range importColumns {
+		name := field
+		if m, ok := mapping[field]; ok {
+			name = m
+		}
+		i, ok := positions[strings.ToLower(strings.TrimSpace(name))]
+		switch {
+		case ok:
+			columns[field] = i
+		case requiredImportColumns[field] || mapping[field] != "":
+			verr.Add("columns["+field+"]", fmt.Sprintf("column %q not found in header", name))
+		}
+	}
+	return columns, verr.OrNil()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func validImportColumn(field string) bool {
+	for _, f := range importColumns {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// importSubscription разбирает строку CSV; ошибки всех полей собираются в одну ValidationError.
+func importSubscription(record []string, columns map[string]int) (*model.Subscription, error) {
+	value := func(field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	verr := &service.ValidationError{}
+	sub := &model.Subscription{
+		ServiceName: value("service_name"),
+		Currency:    value("currency"),
+	}
+	if sub.ServiceName == "" {
+		verr.Add("service_name", "is required")
+	}
+
+	price, err := strconv.ParseInt(value("price"), 10, 64)
+	if err != nil {
+		verr.Add("price", "must be an integer amount in minor units")
+	}
+	sub.Price = price
+
+	if v := value("user_id"); v != "" {
+		if sub.UserID, err = uuid.Parse(v); err != nil {
+			verr.Add("user_id", "must be a UUID")
+		}
+	}
+
+	if sub.StartDate, err = parseMonthYear(value("start_date")); err != nil {
+		verr.Add("start_date", "must be in MM-YYYY format")
+	}
+	if v := value("end_date"); v != "" {
+		ed, err := parseMonthYear(v)
+		if err != nil {
+			verr.Add("end_date", "must be in MM-YYYY format")
+		}
+		sub.EndDate = &ed
+	}
+
+	if err := verr.OrNil(); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func blankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"subscriptions-go/model"
+	"subscriptions-go/service"
+)
+
+type NotificationHandler struct {
+	svc *service.ReminderService
+	log *logrus.Logger
+}
+
+func NewNotificationHandler(svc *service.ReminderService, log *logrus.Logger) *NotificationHandler {
+	return &NotificationHandler{svc: svc, log: log}
+}
+
+type notificationPreferenceReq struct {
+	Email            string `json:"email" binding:"required"`
+	RenewalReminders *bool  `json:"renewal_reminders,omitempty"` // по умолчанию true
+	EndingReminders  *bool  `json:"ending_reminders,omitempty"`  // по умолчанию true
+	DaysBefore       int    `json:"days_before"`                 // 0 — по настройке сервера
+}
+
+// @Summary      Get notification preferences
+// @Description  Настройки напоминаний пользователя о продлении и окончании подписок
+// @Tags         notifications
+// @Produce      json
+// @Param        X-Tenant-ID  header  string  false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        id           path    string  true   "User ID"
+// @Success      200  {object}  model.NotificationPreference
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /users/{id}/notification-preferences [get]
+func (h *NotificationHandler) Get(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+
+	p, err := h.svc.GetPreference(c.Request.Context(), userID)
+	if err != nil {
+		fail(c, h.log, "get notification preferences", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// @Summary      Set notification preferences
+// @Description  Задаёт адрес и настройки напоминаний пользователя. Напоминание о каждом списании и об окончании подписки отправляется один раз за days_before дней до события
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Param        X-Tenant-ID      header  string                     false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string                     false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        id               path    string                     true   "User ID"
+// @Param        preferences      body    notificationPreferenceReq  true   "Notification preferences"
+// @Success      200  {object}  model.NotificationPreference
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /users/{id}/notification-preferences [put]
+func (h *NotificationHandler) Put(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+	var r notificationPreferenceReq
+	if err := c.ShouldBindJSON(&r); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	p := &model.NotificationPreference{
+		UserID:           userID,
+		Email:            r.Email,
+		RenewalReminders: r.RenewalReminders == nil || *r.RenewalReminders,
+		EndingReminders:  r.EndingReminders == nil || *r.EndingReminders,
+		DaysBefore:       r.DaysBefore,
+	}
+	if err := h.svc.SavePreference(c.Request.Context(), p); err != nil {
+		fail(c, h.log, "save notification preferences", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
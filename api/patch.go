@@ -0,0 +1,239 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"subscriptions-go/model"
+	"subscriptions-go/service"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+
+	codeUnsupportedMediaType = "unsupported_media_type"
+)
+
+// patchReq описывает поля, которые принимает PATCH (RFC 7396). Отсутствующее поле
+// не меняется; null сбрасывает end_date и trial_months, а currency, billing_unit
+// и billing_count возвращает к значениям по умолчанию.
+type patchReq struct {
+	ServiceName  *string `json:"service_name,omitempty"`
+	Price        *int64  `json:"price,omitempty"`
+	Currency     *string `json:"currency,omitempty"`
+	UserID       *string `json:"user_id,omitempty"`
+	StartDate    *string `json:"start_date,omitempty"` // MM-YYYY
+	EndDate      *string `json:"end_date,omitempty"`   // MM-YYYY или null
+	BillingUnit  *string `json:"billing_unit,omitempty"`
+	BillingCount *int    `json:"billing_count,omitempty"`
+	TrialMonths  *int    `json:"trial_months,omitempty"` // от start_date; null — без пробного периода
+}
+
+// @Summary      Patch a subscription
+// @Description  Частично обновляет подписку (JSON Merge Patch, RFC 7396): отсутствующие поля не меняются, null очищает end_date и trial_months
+// @Tags         subscriptions
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param        X-Tenant-ID   header  string    false  "Tenant ID; only for platform administrators whose token is not bound to a tenant"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: a repeated request with the same key gets the stored response"
+// @Param        If-Match      header  string    true   "ETag of the subscription from GET, or *"
+// @Param        id            path    string    true   "Subscription ID"
+// @Param        patch         body    patchReq  true   "Merge patch"
+// @Success      200  {object}  model.Subscription
+// @Header       200  {string}  ETag  "Subscription version"
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      412  {object}  Problem
+// @Failure      415  {object}  Problem
+// @Failure      428  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /subscriptions/{id} [patch]
+func (h *Handler) Patch(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		badRequest(c, "id", "must be a UUID")
+		return
+	}
+
+	if c.ContentType() != mergePatchContentType {
+		writeProblem(c, Problem{
+			Status: http.StatusUnsupportedMediaType,
+			Code:   codeUnsupportedMediaType,
+			Detail: "Content-Type must be " + mergePatchContentType,
+		})
+		return
+	}
+
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(c.Request.Body).Decode(&patch); err != nil || patch == nil {
+		writeProblem(c, Problem{Status: http.StatusBadRequest, Code: codeInvalidRequest, Detail: "body must be a JSON object"})
+		return
+	}
+
+	sub, err := h.svc.GetByID(c.Request.Context(), id)
+	if err != nil {
+		h.fail(c, "patch", err)
+		return
+	}
+
+	if err := applyMergePatch(sub, patch); err != nil {
+		h.fail(c, "patch", err)
+		return
+	}
+	if version != nil {
+		sub.Version = *version
+	}
+
+	if err := h.svc.Update(c.Request.Context(), sub); err != nil {
+		h.fail(c, "patch", err)
+		return
+	}
+
+	writeSubscription(c, http.StatusOK, sub)
+}
+
+// applyMergePatch применяет merge patch к подписке. Ошибки всех полей собираются
+// в одну ValidationError; проверку итоговой подписки выполняет сервис.
+func applyMergePatch(sub *model.Subscription, patch map[string]json.RawMessage) error {
+	verr := &service.ValidationError{}
+
+	// Update сравнивает цену с действующей сейчас, а в Price лежит начальная;
+	// без этого патч без price запланировал бы возврат к начальной цене
+	sub.Price = sub.PriceAt(time.Now().UTC())
+
+	// пробный период хранится как первый платный месяц, поэтому при переносе
+	// начала без trial_months сохраняется его длительность
+	trialMonths := -1
+	if sub.TrialEndDate != nil {
+		trialMonths = monthsBetween(sub.StartDate, *sub.TrialEndDate)
+	}
+
+	fields := make([]string, 0, len(patch))
+	for field := range patch {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields) // стабильный порядок ошибок
+
+	for _, field := range fields {
+		raw := patch[field]
+		isNull := bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+
+		switch field {
+		case "service_name":
+			var v string
+			if isNull || json.Unmarshal(raw, &v) != nil || v == "" {
+				verr.Add(field, "must be a non-empty string")
+				continue
+			}
+			sub.ServiceName = v
+		case "price":
+			var v int64
+			if isNull || json.Unmarshal(raw, &v) != nil {
+				verr.Add(field, "must be an integer")
+				continue
+			}
+			sub.Price = v
+		case "currency":
+			var v string
+			if !isNull && json.Unmarshal(raw, &v) != nil {
+				verr.Add(field, "must be a string")
+				continue
+			}
+			sub.Currency = v // пустая — валюта по умолчанию
+		case "user_id":
+			var v string
+			if isNull || json.Unmarshal(raw, &v) != nil {
+				verr.Add(field, "must be a UUID")
+				continue
+			}
+			uid, err := uuid.Parse(v)
+			if err != nil {
+				verr.Add(field, "must be a UUID")
+				continue
+			}
+			sub.UserID = uid
+		case "start_date":
+			t, ok := patchMonth(raw, isNull)
+			if !ok || t == nil {
+				verr.Add(field, "must be in MM-YYYY format")
+				continue
+			}
+			sub.StartDate = *t
+		case "end_date":
+			t, ok := patchMonth(raw, isNull)
+			if !ok {
+				verr.Add(field, "must be in MM-YYYY format or null")
+				continue
+			}
+			sub.EndDate = t
+		case "billing_unit":
+			var v string
+			if !isNull && json.Unmarshal(raw, &v) != nil {
+				verr.Add(field, "must be a string")
+				continue
+			}
+			sub.BillingUnit = v // пустая — month
+		case "billing_count":
+			var v int
+			if !isNull && (json.Unmarshal(raw, &v) != nil || v < 1) {
+				verr.Add(field, "must be a positive integer")
+				continue
+			}
+			sub.BillingCount = v // 0 — раз в единицу периода
+		case "trial_months":
+			var v int
+			if !isNull && (json.Unmarshal(raw, &v) != nil || v < 0) {
+				verr.Add(field, "must be a non-negative integer or null")
+				continue
+			}
+			trialMonths = v
+			if isNull {
+				trialMonths = -1
+			}
+		default:
+			verr.Add(field, "unknown field")
+		}
+	}
+
+	sub.TrialEndDate = nil
+	if trialMonths >= 0 {
+		sub.TrialEndDate = trialEnd(sub.StartDate, trialMonths)
+	}
+	return verr.OrNil()
+}
+
+// patchMonth разбирает месяц MM-YYYY или null (тогда возвращает nil).
+func patchMonth(raw json.RawMessage, isNull bool) (*time.Time, bool) {
+	if isNull {
+		return nil, true
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, false
+	}
+	t, err := parseMonthYear(s)
+	if err != nil {
+		return nil, false
+	}
+	return &t, true
+}
+
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"subscriptions-go/service"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	problemTypePrefix  = "urn:subscriptions:problem:"
+
+	codeInvalidRequest = "invalid_request"
+	codeInternal       = "internal_error"
+)
+
+// Problem — тело ошибки в формате RFC 7807 (application/problem+json).
+type Problem struct {
+	Type          string               `json:"type"`
+	Title         string               `json:"title"`
+	Status        int                  `json:"status"`
+	Detail        string               `json:"detail,omitempty"`
+	Instance      string               `json:"instance,omitempty"`
+	Code          string               `json:"code"`
+	Errors        []service.FieldError `json:"errors,omitempty"`
+	ConflictingID *uuid.UUID           `json:"conflicting_id,omitempty"`
+}
+
+func init() {
+	// в ошибках валидации используем имена полей из JSON, а не из Go-структур
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// fill заполняет тип и заголовок проблемы по коду и статусу.
+func (p *Problem) fill() {
+	p.Type = problemTypePrefix + p.Code
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+}
+
+func writeProblem(c *gin.Context, p Problem) {
+	p.fill()
+	p.Instance = c.Request.URL.Path
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// badRequest отвечает 400 с ошибкой одного поля запроса.
+func badRequest(c *gin.Context, field, message string) {
+	writeProblem(c, Problem{
+		Status: http.StatusBadRequest,
+		Code:   service.CodeValidationFailed,
+		Detail: field + " " + message,
+		Errors: []service.FieldError{{Field: field, Message: message}},
+	})
+}
+
+// bindError отвечает 400 на ошибку разбора или валидации тела запроса.
+func bindError(c *gin.Context, err error) {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		writeProblem(c, Problem{Status: http.StatusBadRequest, Code: codeInvalidRequest, Detail: err.Error()})
+		return
+	}
+
+	fields := make([]service.FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		fields = append(fields, service.FieldError{Field: fieldPath(fe), Message: validationMessage(fe)})
+	}
+	writeProblem(c, Problem{
+		Status: http.StatusBadRequest,
+		Code:   service.CodeValidationFailed,
+		Detail: "request body failed validation",
+		Errors: fields,
+	})
+}
+
+// fieldPath возвращает путь к полю от корня тела запроса, например operations[0].price.
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "required_unless":
+		return "is required"
+	case "min":
+		return "must contain at least " + fe.Param() + " items"
+	case "uuid":
+		return "must be a UUID"
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "len":
+		return "must be " + fe.Param() + " characters long"
+	case "oneof":
+		return "must be one of " + fe.Param()
+	default:
+		return "failed " + fe.Tag() + " validation"
+	}
+}
+
+func (h *Handler) fail(c *gin.Context, op string, err error) {
+	fail(c, h.log, op, err)
+}
+
+// fail отвечает на ошибку сервиса подходящим статусом; неизвестные ошибки логируются и дают 500.
+func fail(c *gin.Context, log *logrus.Logger, op string, err error) {
+	writeProblem(c, problemFor(log, op, err))
+}
+
+// problemFor строит проблему для ошибки сервиса; неизвестные ошибки логируются и дают 500.
+func problemFor(log *logrus.Logger, op string, err error) Problem {
+	var verr *service.ValidationError
+	var conflict *service.ConflictError
+
+	var p Problem
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		p = Problem{Status: http.StatusNotFound, Code: service.CodeNotFound, Detail: err.Error()}
+	case errors.Is(err, service.ErrPreconditionFailed):
+		p = Problem{Status: http.StatusPreconditionFailed, Code: service.CodePrecondition, Detail: err.Error()}
+	case errors.Is(err, service.ErrForbidden):
+		p = Problem{Status: http.StatusForbidden, Code: service.CodeForbidden, Detail: err.Error()}
+	case errors.As(err, &verr):
+		p = Problem{
+			Status: http.StatusBadRequest,
+			Code:   service.CodeValidationFailed,
+			Detail: verr.Error(),
+			Errors: verr.Fields,
+		}
+	case errors.As(err, &conflict):
+		p = Problem{
+			Status:        http.StatusConflict,
+			Code:          conflict.Code,
+			Detail:        conflict.Message,
+			ConflictingID: conflict.ConflictingID,
+		}
+	default:
+		log.Error(op+" error:", err)
+		p = Problem{Status: http.StatusInternalServerError, Code: codeInternal, Detail: op + " failed"}
+	}
+	p.fill()
+	return p
+}
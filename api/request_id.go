@@ -0,0 +1,38 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"subscriptions-go/requestid"
+)
+
+// maxRequestIDLength ограничивает идентификатор, пришедший от клиента, перед записью в журнал аудита.
+const maxRequestIDLength = 128
+
+// RequestID берёт идентификатор запроса из X-Request-ID или генерирует новый,
+// кладёт его в контекст запроса и возвращает в ответе.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		c.Header(requestid.Header, id)
+		c.Request = c.Request.WithContext(requestid.With(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"subscriptions-go/service"
+)
+
+type TenantHandler struct {
+	svc *service.TenantService
+	log *logrus.Logger
+}
+
+func NewTenantHandler(svc *service.TenantService, log *logrus.Logger) *TenantHandler {
+	return &TenantHandler{svc: svc, log: log}
+}
+
+type tenantReq struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// @Summary      Create a tenant
+// @Description  Заводит организацию. Доступно администратору платформы (разрешение tenants:manage); работать в организации он может, передавая её ID в X-Tenant-ID
+// @Tags         tenants
+// @Accept       json
+// @Produce      json
+// @Param        tenant  body  tenantReq  true  "Tenant"
+// @Success      201  {object}  model.Tenant
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /tenants [post]
+func (h *TenantHandler) Create(c *gin.Context) {
+	var r tenantReq
+	if err := c.ShouldBindJSON(&r); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	t, err := h.svc.Create(c.Request.Context(), r.Name)
+	if err != nil {
+		fail(c, h.log, "create tenant", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, t)
+}
+
+// @Summary      List tenants
+// @Description  Все организации по имени. Доступно администратору платформы (разрешение tenants:manage)
+// @Tags         tenants
+// @Produce      json
+// @Success      200  {array}   model.Tenant
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     BearerAuth
+// @Router       /tenants [get]
+func (h *TenantHandler) List(c *gin.Context) {
+	tenants, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		fail(c, h.log, "list tenants", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenants)
+}
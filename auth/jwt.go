@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// ErrUnauthorized возвращается, если токен отсутствует, повреждён или не прошёл проверку.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Config — ключи и ожидаемые значения claims для проверки токенов.
+type Config struct {
+	HS256Secret       string // общий секрет для HS256
+	RS256PublicKeyPEM string // путь к PEM-файлу открытого ключа RS256
+	JWKSFile          string // путь к файлу JWKS с открытыми ключами RS256
+	Issuer            string // если задан, iss должен совпадать
+	Audience          string // если задан, aud должен содержать это значение
+}
+
+// Verifier проверяет bearer-токены (JWT) и извлекает из них вызывающего.
+type Verifier struct {
+	secret  []byte
+	rsaKey  *rsa.PublicKey
+	jwks    map[string]*rsa.PublicKey
+	options []jwt.ParserOption
+}
+
+func NewVerifier(cfg Config) (*Verifier, error) {
+	v := &Verifier{}
+	if cfg.HS256Secret != "" {
+		v.secret = []byte(cfg.HS256Secret)
+	}
+	if cfg.RS256PublicKeyPEM != "" {
+		data, err := os.ReadFile(cfg.RS256PublicKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", cfg.RS256PublicKeyPEM, err)
+		}
+		v.rsaKey = key
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.jwks = keys
+	}
+	if v.secret == nil && v.rsaKey == nil && len(v.jwks) == 0 {
+		return nil, errors.New("no JWT verification keys configured")
+	}
+
+	algs := []string{}
+	if v.secret != nil {
+		algs = append(algs, jwt.SigningMethodHS256.Alg())
+	}
+	if v.rsaKey != nil || len(v.jwks) > 0 {
+		algs = append(algs, jwt.SigningMethodRS256.Alg())
+	}
+	v.options = []jwt.ParserOption{jwt.WithValidMethods(algs), jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		v.options = append(v.options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		v.options = append(v.options, jwt.WithAudience(cfg.Audience))
+	}
+	return v, nil
+}
+
+// claims — claims токена: стандартные и роли вызывающего.
+type claims struct {
+	jwt.RegisteredClaims
+	Roles    []string `json:"roles,omitempty"`
+	TenantID string   `json:"tenant_id,omitempty"`
+}
+
+// Verify проверяет подпись и claims токена; sub должен быть UUID пользователя.
+// Неизвестные роли игнорируются, без ролей вызывающий получает роль user.
+func (v *Verifier) Verify(token string) (*Principal, error) {
+	var c claims
+	if _, err := jwt.ParseWithClaims(token, &c, v.key, v.options...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+
+	userID, err := uuid.Parse(c.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: sub must be a user UUID", ErrUnauthorized)
+	}
+
+	p := &Principal{UserID: userID, Subject: c.Subject}
+	if c.TenantID != "" {
+		tenantID, err := uuid.Parse(c.TenantID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: tenant_id must be a UUID", ErrUnauthorized)
+		}
+		p.TenantID = &tenantID
+	}
+	for _, r := range c.Roles {
+		if role := Role(r); ValidRole(role) {
+			p.Roles = append(p.Roles, role)
+		}
+	}
+	if len(p.Roles) == 0 {
+		p.Roles = []Role{RoleUser}
+	}
+	return p, nil
+}
+
+func (v *Verifier) key(t *jwt.Token) (interface{}, error) {
+	switch t.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		if kid, _ := t.Header["kid"].(string); kid != "" && v.jwks != nil {
+			if key, ok := v.jwks[kid]; ok {
+				return key, nil
+			}
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		if v.rsaKey != nil {
+			return v.rsaKey, nil
+		}
+		if len(v.jwks) == 1 {
+			for _, key := range v.jwks {
+				return key, nil
+			}
+		}
+		return nil, errors.New("token has no key id")
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+}
+
+type jwkSet struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// loadJWKS читает RSA-ключи подписи из JWKS-файла (RFC 7517).
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %q: invalid n", path, k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %q: invalid e", path, k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no RS256 signing keys", path)
+	}
+	return keys, nil
+}
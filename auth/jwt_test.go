@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const testSecret = "test-secret"
+
+var (
+	testUser   = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	testTenant = uuid.MustParse("22222222-2222-2222-2222-222222222222")
+)
+
+// validClaims возвращает claims действующего токена; tweak меняет их перед подписью.
+func validClaims(tweak func(jwt.MapClaims)) jwt.MapClaims {
+	c := jwt.MapClaims{
+		"sub": testUser.String(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iss": "https://issuer.example",
+		"aud": "subscriptions",
+	}
+	if tweak != nil {
+		tweak(c)
+	}
+	return c
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, claims jwt.MapClaims, kid string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func publicKeyPEM(t *testing.T, key *rsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestVerifyHS256(t *testing.T) {
+	v, err := NewVerifier(Config{HS256Secret: testSecret, Issuer: "https://issuer.example", Audience: "subscriptions"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte(testSecret)
+
+	tests := []struct {
+		name  string
+		token string
+		want  *Principal // nil — токен отклоняется
+	}{
+		{
+			name:  "valid",
+			token: sign(t, jwt.SigningMethodHS256, secret, validClaims(nil), ""),
+			want:  &Principal{UserID: testUser, Subject: testUser.String(), Roles: []Role{RoleUser}},
+		},
+		{
+			name: "roles and tenant",
+			token: sign(t, jwt.SigningMethodHS256, secret, validClaims(func(c jwt.MapClaims) {
+				c["roles"] = []string{"finance", "superuser", "admin"}
+				c["tenant_id"] = testTenant.String()
+			}), ""),
+			want: &Principal{UserID: testUser, Subject: testUser.String(), Roles: []Role{RoleFinance, RoleAdmin}, TenantID: &testTenant},
+		},
+		{
+			name: "only unknown roles",
+			token: sign(t, jwt.SigningMethodHS256, secret, validClaims(func(c jwt.MapClaims) {
+				c["roles"] = []string{"root", "Admin"}
+			}), ""),
+			want: &Principal{UserID: testUser, Subject: testUser.String(), Roles: []Role{RoleUser}},
+		},
+		{
+			name: "missing exp",
+			token: sign(t, jwt.SigningMethodHS256, secret, validClaims(func(c jwt.MapClaims) {
+				delete(c, "exp")
+			}), ""),
+		},
+		{
+			name: "expired",
+			token: sign(t, jwt.SigningMethodHS256, secret, validClaims(func(c jwt.MapClaims) {
+				c["exp"] = time.Now().Add(-time.Minute).Unix()
+			}), ""),
+		},
+		{
+			name: "wrong issuer",
+			token: sign(t, jwt.SigningMethodHS256, secret, validClaims(func(c jwt.MapClaims) {
+				c["iss"] = "https://evil.example"
+			}), ""),
+		},
+		{
+			name: "wrong audience",
+			token: sign(t, jwt.SigningMethodHS256, secret, validClaims(func(c jwt.MapClaims) {
+				c["aud"] = "billing"
+			}), ""),
+		},
+		{
+			name: "sub is not a UUID",
+			token: sign(t, jwt.SigningMethodHS256, secret, validClaims(func(c jwt.MapClaims) {
+				c["sub"] = "alice"
+			}), ""),
+		},
+		{
+			name: "tenant_id is not a UUID",
+			token: sign(t, jwt.SigningMethodHS256, secret, validClaims(func(c jwt.MapClaims) {
+				c["tenant_id"] = "acme"
+			}), ""),
+		},
+		{
+			name:  "wrong secret",
+			token: sign(t, jwt.SigningMethodHS256, []byte("other-secret"), validClaims(nil), ""),
+		},
+		{
+			name:  "alg none",
+			token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims(nil), ""),
+		},
+		{
+			name:  "HS512 is not accepted",
+			token: sign(t, jwt.SigningMethodHS512, secret, validClaims(nil), ""),
+		},
+		{
+			name:  "RS256 without a configured key",
+			token: sign(t, jwt.SigningMethodRS256, rsaKey(t), validClaims(nil), ""),
+		},
+		{
+			name:  "garbage",
+			token: "not.a.token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Verify(tt.token)
+			if tt.want == nil {
+				if !errors.Is(err, ErrUnauthorized) {
+					t.Errorf("Verify() = %+v, %v, want ErrUnauthorized", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Verify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRS256(t *testing.T) {
+	key := rsaKey(t)
+	pub := publicKeyPEM(t, key)
+	v, err := NewVerifier(Config{RS256PublicKeyPEM: writeFile(t, "key.pem", pub)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.Verify(sign(t, jwt.SigningMethodRS256, key, validClaims(nil), "")); err != nil {
+		t.Errorf("valid RS256 token: %v", err)
+	}
+	// подмена алгоритма: HS256, подписанный открытым ключом как секретом
+	forged := sign(t, jwt.SigningMethodHS256, pub, validClaims(nil), "")
+	if _, err := v.Verify(forged); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("HS256 token signed with the public key: err = %v, want ErrUnauthorized", err)
+	}
+	if _, err := v.Verify(sign(t, jwt.SigningMethodRS256, rsaKey(t), validClaims(nil), "")); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("token signed with another key: err = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestVerifyJWKS(t *testing.T) {
+	first, second := rsaKey(t), rsaKey(t)
+	jwk := func(kid string, key *rsa.PrivateKey) string {
+		e := big.NewInt(int64(key.PublicKey.E)).Bytes()
+		return fmt.Sprintf(`{"kty":"RSA","kid":%q,"use":"sig","alg":"RS256","n":%q,"e":%q}`, kid,
+			base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()), base64.RawURLEncoding.EncodeToString(e))
+	}
+	jwks := `{"keys":[` + jwk("k1", first) + `,` + jwk("k2", second) + `,{"kty":"EC","kid":"ec"}]}`
+	v, err := NewVerifier(Config{JWKSFile: writeFile(t, "jwks.json", []byte(jwks))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"first key", sign(t, jwt.SigningMethodRS256, first, validClaims(nil), "k1"), true},
+		{"second key", sign(t, jwt.SigningMethodRS256, second, validClaims(nil), "k2"), true},
+		{"key id of another key", sign(t, jwt.SigningMethodRS256, first, validClaims(nil), "k2"), false},
+		{"unknown key id", sign(t, jwt.SigningMethodRS256, first, validClaims(nil), "k3"), false},
+		{"no key id with several keys", sign(t, jwt.SigningMethodRS256, first, validClaims(nil), ""), false},
+		{"HS256 is not configured", sign(t, jwt.SigningMethodHS256, []byte(testSecret), validClaims(nil), ""), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Verify(tt.token)
+			if tt.ok && err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrUnauthorized) {
+				t.Errorf("Verify() error = %v, want ErrUnauthorized", err)
+			}
+		})
+	}
+}
+
+func TestNewVerifierWithoutKeys(t *testing.T) {
+	if _, err := NewVerifier(Config{Issuer: "https://issuer.example"}); err == nil {
+		t.Error("NewVerifier() without keys succeeded")
+	}
+}
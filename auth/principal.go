@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Principal — аутентифицированный вызывающий.
+type Principal struct {
+	UserID   uuid.UUID
+	Subject  string
+	Roles    []Role
+	Scopes   []Permission // разрешения, выданные напрямую (API-ключ)
+	TenantID *uuid.UUID   // организация из токена; nil — токен не привязан к организации
+}
+
+type principalKey struct{}
+
+// WithPrincipal кладёт вызывающего в контекст запроса.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает вызывающего из контекста. ok == false, если запрос
+// не аутентифицирован (например, при AUTH_DISABLED).
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// ScopeUserID возвращает пользователя, которым ограничены данные вызывающего,
+// или nil, если ограничения нет (нет вызывающего или ему доступны все пользователи).
+func ScopeUserID(ctx context.Context) *uuid.UUID {
+	p, ok := FromContext(ctx)
+	if !ok || p.Can(PermReadAll) {
+		return nil
+	}
+	return &p.UserID
+}
+
+// Actor возвращает идентификатор вызывающего для журнала аудита.
+func Actor(ctx context.Context) string {
+	if p, ok := FromContext(ctx); ok {
+		return p.Subject
+	}
+	return "anonymous"
+}
@@ -0,0 +1,80 @@
+package auth
+
+import "context"
+
+// Role — роль вызывающего, передаётся в claim roles токена.
+type Role string
+
+const (
+	RoleUser            Role = "user"             // свои подписки
+	RoleSupportReadOnly Role = "support-readonly" // чтение подписок всех пользователей
+	RoleFinance         Role = "finance"          // чтение и сводки по всем пользователям
+	RoleAdmin           Role = "admin"            // полный доступ
+	RolePlatformAdmin   Role = "platform-admin"   // заведение организаций и выбор организации через X-Tenant-ID
+)
+
+// Permission — действие, которое проверяется перед обработчиком маршрута.
+type Permission string
+
+const (
+	PermRead       Permission = "subscriptions:read"        // читать доступные подписки
+	PermWrite      Permission = "subscriptions:write"       // изменять доступные подписки
+	PermReadAll    Permission = "subscriptions:read_all"    // доступны подписки всех пользователей
+	PermWriteAll   Permission = "subscriptions:write_all"   // изменять подписки любых пользователей
+	PermSummaryAll Permission = "subscriptions:summary_all" // сводка по нескольким пользователям
+	PermDeleted    Permission = "subscriptions:deleted"     // просмотр удалённых подписок организации
+	PermAPIKeys    Permission = "api_keys:manage"           // выпуск и отзыв API-ключей
+	PermAudit      Permission = "audit:read"                // журнал изменений организации
+	PermTenants    Permission = "tenants:manage"            // заведение организаций, работа в любой из них
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleUser:            {PermRead, PermWrite},
+	RoleSupportReadOnly: {PermRead, PermReadAll},
+	RoleFinance:         {PermRead, PermReadAll, PermSummaryAll, PermAudit},
+	RoleAdmin:           {PermRead, PermWrite, PermReadAll, PermWriteAll, PermSummaryAll, PermDeleted, PermAPIKeys, PermAudit},
+	RolePlatformAdmin:   {PermTenants},
+}
+
+// ValidPermission сообщает, известно ли разрешение.
+func ValidPermission(perm Permission) bool {
+	for _, perms := range rolePermissions {
+		for _, p := range perms {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidRole сообщает, известна ли роль.
+func ValidRole(r Role) bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can сообщает, есть ли у вызывающего разрешение: выданное напрямую
+// (scopes API-ключа) или хотя бы через одну из его ролей.
+func (p *Principal) Can(perm Permission) bool {
+	for _, granted := range p.Scopes {
+		if granted == perm {
+			return true
+		}
+	}
+	for _, r := range p.Roles {
+		for _, granted := range rolePermissions[r] {
+			if granted == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Can сообщает, есть ли разрешение у вызывающего из ctx.
+// Без вызывающего (аутентификация отключена) разрешено всё.
+func Can(ctx context.Context, perm Permission) bool {
+	p, ok := FromContext(ctx)
+	return !ok || p.Can(perm)
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCan(t *testing.T) {
+	all := []Permission{PermRead, PermWrite, PermReadAll, PermWriteAll, PermSummaryAll, PermDeleted, PermAPIKeys, PermAudit, PermTenants}
+	tests := []struct {
+		name    string
+		p       *Principal
+		granted []Permission
+	}{
+		{"user", &Principal{Roles: []Role{RoleUser}}, []Permission{PermRead, PermWrite}},
+		{"support", &Principal{Roles: []Role{RoleSupportReadOnly}}, []Permission{PermRead, PermReadAll}},
+		{"finance", &Principal{Roles: []Role{RoleFinance}}, []Permission{PermRead, PermReadAll, PermSummaryAll, PermAudit}},
+		{"admin", &Principal{Roles: []Role{RoleAdmin}},
+			[]Permission{PermRead, PermWrite, PermReadAll, PermWriteAll, PermSummaryAll, PermDeleted, PermAPIKeys, PermAudit}},
+		{"platform admin", &Principal{Roles: []Role{RolePlatformAdmin}}, []Permission{PermTenants}},
+		{"roles combine", &Principal{Roles: []Role{RoleUser, RoleSupportReadOnly}}, []Permission{PermRead, PermWrite, PermReadAll}},
+		{"unknown role", &Principal{Roles: []Role{"root"}}, nil},
+		{"api key scopes", &Principal{Scopes: []Permission{PermRead, PermAudit}}, []Permission{PermRead, PermAudit}},
+		{"no roles", &Principal{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			granted := map[Permission]bool{}
+			for _, perm := range tt.granted {
+				granted[perm] = true
+			}
+			for _, perm := range all {
+				if got := tt.p.Can(perm); got != granted[perm] {
+					t.Errorf("Can(%s) = %v, want %v", perm, got, granted[perm])
+				}
+			}
+		})
+	}
+}
+
+func TestCanWithoutPrincipal(t *testing.T) {
+	// без вызывающего аутентификация отключена, разрешено всё
+	if !Can(context.Background(), PermWriteAll) {
+		t.Error("Can() without a principal = false")
+	}
+	ctx := WithPrincipal(context.Background(), &Principal{Roles: []Role{RoleUser}})
+	if Can(ctx, PermWriteAll) {
+		t.Error("Can(write_all) for a user = true")
+	}
+}
+
+func TestScopeUserID(t *testing.T) {
+	userID := uuid.New()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want *uuid.UUID
+	}{
+		{"no principal", context.Background(), nil},
+		{"user", WithPrincipal(context.Background(), &Principal{UserID: userID, Roles: []Role{RoleUser}}), &userID},
+		{"support", WithPrincipal(context.Background(), &Principal{UserID: userID, Roles: []Role{RoleSupportReadOnly}}), nil},
+		{"api key with read only", WithPrincipal(context.Background(), &Principal{UserID: userID, Scopes: []Permission{PermRead}}), &userID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ScopeUserID(tt.ctx)
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("ScopeUserID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidRoleAndPermission(t *testing.T) {
+	for _, r := range []Role{RoleUser, RoleSupportReadOnly, RoleFinance, RoleAdmin, RolePlatformAdmin} {
+		if !ValidRole(r) {
+			t.Errorf("ValidRole(%s) = false", r)
+		}
+	}
+	if ValidRole("Admin") || ValidRole("") {
+		t.Error("ValidRole() accepts unknown roles")
+	}
+	if !ValidPermission(PermAudit) || ValidPermission("subscriptions:*") {
+		t.Error("ValidPermission() is wrong")
+	}
+}
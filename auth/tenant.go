@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type tenantKey struct{}
+
+// WithTenant кладёт организацию, в которой выполняется запрос, в контекст.
+func WithTenant(ctx context.Context, tenantID uuid.UUID) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantID возвращает организацию запроса. ok == false, если она не определена.
+func TenantID(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(tenantKey{}).(uuid.UUID)
+	return id, ok && id != uuid.Nil
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -11,9 +13,11 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 
 	"subscriptions-go/api"
+	"subscriptions-go/auth"
 	"subscriptions-go/config"
 	"subscriptions-go/db"
-	"subscriptions-go/model"
+	"subscriptions-go/money"
+	"subscriptions-go/notify"
 	"subscriptions-go/repository"
 	"subscriptions-go/service"
 
@@ -25,6 +29,10 @@ import (
 // @description API для управления подписками
 // @host localhost:8000
 // @BasePath /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Bearer <JWT> или ApiKey <ключ>
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
@@ -40,23 +48,146 @@ func main() {
 		log.Fatal(err)
 	}
 
-	if err := gormDB.AutoMigrate(&model.Subscription{}); err != nil {
-		log.Fatal("auto migrate failed:", err)
+	if err := db.Migrate(context.Background(), gormDB); err != nil {
+		log.Fatal("migrate failed:", err)
+	}
+
+	rates, err := money.LoadRatesFile(cfg.RatesFile)
+	if err != nil {
+		log.Fatal("load exchange rates failed:", err)
+	}
+	if currency := money.Normalize(cfg.DefaultCurrency); !money.Valid(currency) {
+		log.Fatalf("unknown DEFAULT_CURRENCY %q", cfg.DefaultCurrency)
+	} else if _, err := rates.Rate(currency, currency); err != nil {
+		log.Fatalf("DEFAULT_CURRENCY %q: %v", cfg.DefaultCurrency, err)
 	}
 
 	repo := repository.NewSubscriptionRepo(gormDB)
-	svc := service.NewSubscriptionService(repo)
+	svc := service.NewSubscriptionService(repo, rates, cfg.DefaultCurrency)
 	handler := api.NewHandler(svc, log)
+	keySvc := service.NewAPIKeyService(repository.NewAPIKeyRepo(gormDB))
+	keyHandler := api.NewAPIKeyHandler(keySvc, log)
+	auditHandler := api.NewAuditHandler(service.NewAuditService(repository.NewAuditRepo(gormDB)), log)
+	candidateHandler := api.NewCandidateHandler(service.NewCandidateService(repository.NewCandidateRepo(gormDB), svc), log)
+	tenants := repository.NewTenantRepo(gormDB)
+	tenantHandler := api.NewTenantHandler(service.NewTenantService(tenants), log)
+	calendarHandler := api.NewCalendarHandler(service.NewCalendarService(repository.NewCalendarTokenRepo(gormDB), repo), log)
+
+	idempotencyKeys := repository.NewIdempotencyRepo(gormDB)
+
+	var notifier notify.Notifier
+	if cfg.SMTPAddr != "" {
+		smtp, err := notify.NewSMTP(notify.SMTPConfig{
+			Addr:     cfg.SMTPAddr,
+			From:     cfg.SMTPFrom,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+		})
+		if err != nil {
+			log.Fatal("configure smtp failed:", err)
+		}
+		notifier = smtp
+	}
+	notifications := repository.NewNotificationRepo(gormDB)
+	reminders := service.NewReminderService(notifications, repo, notifier, cfg.ReminderWindow)
+	notificationHandler := api.NewNotificationHandler(reminders, log)
+
+	if cfg.PurgeRetention > 0 && cfg.PurgeInterval > 0 {
+		go runPurge(context.Background(), "deleted subscriptions", func(ctx context.Context) (int64, error) {
+			return svc.PurgeDeleted(ctx, cfg.PurgeRetention)
+		}, cfg.PurgeInterval, log)
+	}
+	if cfg.IdempotencyTTL > 0 && cfg.PurgeInterval > 0 {
+		go runPurge(context.Background(), "expired idempotency keys", func(ctx context.Context) (int64, error) {
+			return idempotencyKeys.PurgeExpired(ctx, time.Now())
+		}, cfg.PurgeInterval, log)
+	}
+	if cfg.PurgeInterval > 0 {
+		// напоминания о прошедших событиях больше не отправляются, отметки о них не нужны
+		go runPurge(context.Background(), "reminder log entries", func(ctx context.Context) (int64, error) {
+			return notifications.PurgeReminderLog(ctx, time.Now().AddDate(0, 0, -1))
+		}, cfg.PurgeInterval, log)
+	}
+	if notifier == nil || cfg.ReminderInterval == 0 {
+		log.Info("reminders are disabled: set SMTP_ADDR and REMINDER_INTERVAL to enable them")
+	} else {
+		go runReminders(context.Background(), reminders, cfg.ReminderInterval, log)
+	}
+
+	r := gin.New()
+	r.Use(api.AccessLog(), gin.Recovery(), api.RequestID())
+
+	secured := r.Group("/")
+	// организации заводятся вне какой-либо организации, поэтому без ResolveTenant
+	platform := r.Group("/tenants")
+	require := api.Require
+	if cfg.AuthDisabled {
+		log.Warn("AUTH_DISABLED=true: API is not authenticated")
+		require = func(auth.Permission) gin.HandlerFunc { return func(c *gin.Context) { c.Next() } }
+	} else {
+		verifier, err := auth.NewVerifier(auth.Config{
+			HS256Secret:       cfg.JWTSecret,
+			RS256PublicKeyPEM: cfg.JWTPublicKeyFile,
+			JWKSFile:          cfg.JWTJWKSFile,
+			Issuer:            cfg.JWTIssuer,
+			Audience:          cfg.JWTAudience,
+		})
+		if err != nil {
+			log.Fatal("configure authentication failed:", err)
+		}
+		authenticate := api.Authenticate(verifier, keySvc, log)
+		secured.Use(authenticate)
+		platform.Use(authenticate)
+	}
+	secured.Use(api.ResolveTenant(tenants, log))
+	if cfg.IdempotencyTTL > 0 {
+		secured.Use(api.Idempotency(idempotencyKeys, cfg.IdempotencyTTL, log))
+	}
+
+	subs := secured.Group("/subscriptions")
+	read, write := require(auth.PermRead), require(auth.PermWrite)
+	subs.POST("", write, handler.Create)
+	subs.GET("", read, handler.List)
+	subs.GET("/deleted", require(auth.PermDeleted), handler.ListDeleted)
+	subs.POST("/import", write, handler.Import)
+	subs.GET("/:id", read, handler.Get)
+	subs.GET("/summary", read, handler.Summary)
+	subs.GET("/trials", read, handler.Trials)
+	subs.PUT("/:id", write, handler.Update)
+	subs.PATCH("/:id", write, handler.Patch)
+	subs.DELETE("/:id", write, handler.Delete)
+	subs.POST("/:id/prices", write, handler.SchedulePriceChange)
+	subs.POST("/:id/pause", write, handler.Pause)
+	subs.POST("/:id/resume", write, handler.Resume)
+	subs.POST("/:id/restore", write, handler.Restore)
+	subs.GET("/:id/history", read, auditHandler.History)
+	secured.POST("/subscriptions:action", write, handler.Action) // POST /subscriptions:batch
+
+	secured.POST("/statements/import", write, candidateHandler.ImportStatement)
+	candidates := secured.Group("/subscription-candidates")
+	candidates.GET("", read, candidateHandler.List)
+	candidates.POST("/:id/accept", write, candidateHandler.Accept)
+	candidates.POST("/:id/dismiss", write, candidateHandler.Dismiss)
+
+	secured.GET("/audit", require(auth.PermAudit), auditHandler.Search)
+
+	keys := secured.Group("/api-keys", require(auth.PermAPIKeys))
+	keys.POST("", keyHandler.Create)
+	keys.GET("", keyHandler.List)
+	keys.POST("/:id/rotate", keyHandler.Rotate)
+	keys.DELETE("/:id", keyHandler.Revoke)
+
+	users := secured.Group("/users")
+	users.POST("/:id/calendar-token", write, calendarHandler.IssueToken)
+	users.DELETE("/:id/calendar-token", write, calendarHandler.RevokeToken)
+	users.GET("/:id/notification-preferences", read, notificationHandler.Get)
+	users.PUT("/:id/notification-preferences", write, notificationHandler.Put)
+	// лента защищена собственным токеном в ссылке: клиенты календаря не умеют слать Authorization
+	r.GET("/users/:id/renewals.ics", calendarHandler.Feed)
 
-	r := gin.Default()
+	platform.POST("", require(auth.PermTenants), tenantHandler.Create)
+	platform.GET("", require(auth.PermTenants), tenantHandler.List)
 
-	r.POST("/subscriptions", handler.Create)
-	r.GET("/subscriptions", handler.List)
-	r.GET("/subscriptions/:id", handler.Get)
-	r.GET("/subscriptions/summary", handler.Summary)
-	r.PUT("/subscriptions/:id", handler.Update)
-	r.DELETE("/subscriptions/:id", handler.Delete)
-	// TODO: add GET /subscriptions, GET/PUT/DELETE /subscriptions/:id (implement in handler)
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	port := strconv.Itoa(cfg.AppPort)
 	addr := fmt.Sprintf("%s:%s", cfg.AppHost, port)
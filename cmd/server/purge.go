@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runPurge раз в interval вызывает purge и пишет в журнал, сколько записей удалено.
+func runPurge(ctx context.Context, what string, purge func(context.Context) (int64, error), interval time.Duration, log *logrus.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := purge(ctx)
+		if err != nil {
+			log.Errorf("purge %s error: %v", what, err)
+		} else if n > 0 {
+			log.Infof("purged %d %s", n, what)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"subscriptions-go/service"
+)
+
+// runReminders раз в interval отправляет напоминания, срок которых подошёл.
+func runReminders(ctx context.Context, reminders *service.ReminderService, interval time.Duration, log *logrus.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := reminders.SendDue(ctx, time.Now())
+		if err != nil {
+			log.Errorf("send reminders error: %v", err)
+		}
+		if n > 0 {
+			log.Infof("sent %d reminders", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,6 +14,29 @@ type Config struct {
 	AppHost     string
 	AppPort     int
 	LogLevel    string
+
+	RatesFile       string
+	DefaultCurrency string
+
+	AuthDisabled     bool   // только для локальной разработки: API без аутентификации
+	JWTSecret        string // HS256
+	JWTPublicKeyFile string // PEM с открытым ключом RS256
+	JWTJWKSFile      string // JWKS с открытыми ключами RS256
+	JWTIssuer        string
+	JWTAudience      string
+
+	PurgeRetention time.Duration // сколько хранятся удалённые подписки; 0 — не очищать
+	PurgeInterval  time.Duration
+
+	IdempotencyTTL time.Duration // сколько хранится ответ на запрос с Idempotency-Key; 0 — заголовок не поддерживается
+
+	ReminderInterval time.Duration // как часто искать события для напоминаний; 0 — не напоминать
+	ReminderWindow   time.Duration // за сколько до продления или окончания подписки напоминать
+
+	SMTPAddr     string // host:port; пусто — напоминания не отправляются
+	SMTPFrom     string
+	SMTPUsername string
+	SMTPPassword string
 }
 
 func Load() (*Config, error) {
@@ -24,11 +49,57 @@ func Load() (*Config, error) {
 		}
 	}
 
+	purgeRetention, err := getduration("PURGE_RETENTION", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	purgeInterval, err := getduration("PURGE_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	idempotencyTTL, err := getduration("IDEMPOTENCY_TTL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	reminderInterval, err := getduration("REMINDER_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	reminderWindow, err := getduration("REMINDER_WINDOW", 72*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		DatabaseURL: getenv("DATABASE_URL", os.Getenv("DATABASE_URL")),
 		AppHost:     getenv("APP_HOST", "0.0.0.0"),
 		AppPort:     port,
 		LogLevel:    getenv("LOG_LEVEL", "info"),
+
+		RatesFile:       getenv("RATES_FILE", "rates.json"),
+		DefaultCurrency: getenv("DEFAULT_CURRENCY", "RUB"),
+
+		AuthDisabled:     os.Getenv("AUTH_DISABLED") == "true",
+		JWTSecret:        os.Getenv("JWT_HS256_SECRET"),
+		JWTPublicKeyFile: os.Getenv("JWT_RS256_PUBLIC_KEY_FILE"),
+		JWTJWKSFile:      os.Getenv("JWT_JWKS_FILE"),
+		JWTIssuer:        os.Getenv("JWT_ISSUER"),
+		JWTAudience:      os.Getenv("JWT_AUDIENCE"),
+
+		PurgeRetention: purgeRetention,
+		PurgeInterval:  purgeInterval,
+
+		IdempotencyTTL: idempotencyTTL,
+
+		ReminderInterval: reminderInterval,
+		ReminderWindow:   reminderWindow,
+
+		SMTPAddr:     os.Getenv("SMTP_ADDR"),
+		SMTPFrom:     getenv("SMTP_FROM", "Subscriptions <noreply@localhost>"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
 	}, nil
 }
 
@@ -38,3 +109,15 @@ func getenv(k, def string) string {
 	}
 	return def
 }
+
+func getduration(k string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(k)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s: invalid duration %q", k, v)
+	}
+	return d, nil
+}
@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// createSchemaMigrations создаёт таблицу версий в том же виде, что golang-migrate.
+const createSchemaMigrations = "CREATE TABLE schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)"
+
+// migrationLock — ключ advisory-блокировки, чтобы несколько экземпляров сервиса
+// не применяли миграции одновременно.
+const migrationLock = 7_271_915_336
+
+type migration struct {
+	version int64
+	name    string
+}
+
+// Migrate применяет к базе ещё не применённые миграции из db/migrations, каждую
+// в своей транзакции. Версия схемы хранится в таблице schema_migrations в том же
+// виде, что у golang-migrate, поэтому откатывать миграции можно его CLI.
+// Схему задают только миграции: ограничения вроде subscriptions_no_overlap
+// AutoMigrate не создаёт. Схема, созданная AutoMigrate до перехода на миграции,
+// принимается за версию 1 (см. adoptLegacySchema).
+func Migrate(ctx context.Context, gormDB *gorm.DB) error {
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLock)
+
+	current, err := schemaVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	pending, err := pendingMigrations(current)
+	if err != nil {
+		return err
+	}
+	for _, m := range pending {
+		if err := apply(ctx, conn, m); err != nil {
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+	}
+	return nil
+}
+
+// schemaVersion возвращает версию схемы, создавая таблицу schema_migrations при первом запуске.
+func schemaVersion(ctx context.Context, conn *sql.Conn) (int64, error) {
+	exists, err := tableExists(ctx, conn, "schema_migrations")
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		legacy, err := tableExists(ctx, conn, "subscriptions")
+		if err != nil {
+			return 0, err
+		}
+		if legacy {
+			return adoptLegacySchema(ctx, conn)
+		}
+		_, err = conn.ExecContext(ctx, createSchemaMigrations)
+		return 0, err
+	}
+
+	var version int64
+	var dirty bool
+	err = conn.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("schema_migrations: version %d is dirty, fix the schema and clear the flag manually", version)
+	}
+	return version, nil
+}
+
+// legacyColumns — столбцы subscriptions в схеме, которую до перехода на миграции
+// создавал AutoMigrate. Она совпадает с миграцией 0001.
+var legacyColumns = []string{"created_at", "end_date", "id", "price", "service_name", "start_date", "user_id"}
+
+// adoptLegacySchema принимает схему, созданную AutoMigrate до перехода на миграции,
+// за версию 1: остальные миграции, начиная с пересчёта цен в 0002, применятся к ней
+// обычным порядком. Схему другого вида принять нельзя — она заполнена неизвестно чем.
+func adoptLegacySchema(ctx context.Context, conn *sql.Conn) (int64, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'subscriptions' ORDER BY column_name`)
+	if err != nil {
+		return 0, err
+	}
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		columns = append(columns, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if strings.Join(columns, ",") != strings.Join(legacyColumns, ",") {
+		return 0, fmt.Errorf("subscriptions table exists but schema_migrations does not, and its columns (%s) "+
+			"do not match migration 0001: bring the schema in line with db/migrations and record "+
+			"the version in schema_migrations before starting the service", strings.Join(columns, ", "))
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// 0001 написана через IF NOT EXISTS и на такой схеме лишь создаёт расширение;
+	// значение id по умолчанию AutoMigrate не задавал
+	body, err := migrations.ReadFile("migrations/0001_create_subscriptions_table.up.sql")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, string(body)); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "ALTER TABLE subscriptions ALTER COLUMN id SET DEFAULT uuid_generate_v4()"); err != nil {
+		return 0, err
+	}
+	// schema_migrations создаётся вместе с отметкой о версии 1: иначе после сбоя
+	// следующий запуск применил бы 0002 к этим данным с нуля
+	if _, err := tx.ExecContext(ctx, createSchemaMigrations); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES (1, false)"); err != nil {
+		return 0, err
+	}
+	return 1, tx.Commit()
+}
+
+// tableExists сообщает, есть ли таблица name в текущей схеме.
+func tableExists(ctx context.Context, conn *sql.Conn, name string) (bool, error) {
+	var exists bool
+	err := conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_name = $1)`, name).Scan(&exists)
+	return exists, err
+}
+
+// pendingMigrations возвращает миграции новее версии current по возрастанию версии.
+func pendingMigrations(current int64) ([]migration, error) {
+	names, err := fs.Glob(migrations, "migrations/*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []migration
+	for _, name := range names {
+		base := strings.TrimPrefix(name, "migrations/")
+		prefix, _, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: file name must start with a version number", base)
+		}
+		if version > current {
+			pending = append(pending, migration{version: version, name: name})
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].version < pending[j].version })
+	return pending, nil
+}
+
+func apply(ctx context.Context, conn *sql.Conn, m migration) error {
+	body, err := migrations.ReadFile(m.name)
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// без параметров pgx выполняет запрос простым протоколом, так что в файле может быть несколько команд
+	if _, err := tx.ExecContext(ctx, string(body)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)", m.version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
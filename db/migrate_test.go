@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestPendingMigrations(t *testing.T) {
+	all, err := pendingMigrations(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) == 0 || all[0].version != 1 {
+		t.Fatalf("pendingMigrations(0) = %v, want migrations from 0001", all)
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].version != all[i-1].version+1 {
+			t.Errorf("migration %s follows %s: versions must be consecutive", all[i].name, all[i-1].name)
+		}
+		if !strings.HasSuffix(all[i].name, ".up.sql") {
+			t.Errorf("migration %s is not an up migration", all[i].name)
+		}
+	}
+
+	rest, err := pendingMigrations(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != len(all)-1 || rest[0].version != 2 {
+		t.Errorf("pendingMigrations(1) = %v, want migrations from 0002", rest)
+	}
+}
+
+// testSchema открывает тестовую базу TEST_DATABASE_URL с пустой схемой, которая
+// удаляется по завершении теста. Без TEST_DATABASE_URL тест пропускается.
+func testSchema(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	cfg := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+
+	admin, err := gorm.Open(postgres.Open(dsn), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := "migrate_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	// расширения остаются в public, поэтому он тоже в search_path
+	sep := " "
+	if strings.Contains(dsn, "://") {
+		sep = "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+	}
+	db, err := gorm.Open(postgres.Open(dsn+sep+"search_path="+schema+",public"), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// baselineSubscription — модель подписки до перехода на миграции; её таблицу
+// создавал AutoMigrate при запуске сервиса.
+type baselineSubscription struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;"`
+	ServiceName string     `gorm:"type:varchar(200);not null;index"`
+	Price       int        `gorm:"not null"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index"`
+	StartDate   time.Time  `gorm:"type:date;not null"`
+	EndDate     *time.Time `gorm:"type:date"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+}
+
+func (baselineSubscription) TableName() string { return "subscriptions" }
+
+func TestMigrateAdoptsAutoMigrateSchema(t *testing.T) {
+	db := testSchema(t)
+	ctx := context.Background()
+
+	if err := db.AutoMigrate(&baselineSubscription{}); err != nil {
+		t.Fatal(err)
+	}
+	sub := baselineSubscription{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 399, UserID: uuid.New(), StartDate: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)}
+	if err := db.Create(&sub).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// второй запуск ничего не применяет повторно: цены переводятся в копейки один раз
+	for run := 1; run <= 2; run++ {
+		if err := Migrate(ctx, db); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+
+	latest, err := pendingMigrations(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var version int64
+	var dirty bool
+	if err := db.Raw("SELECT version, dirty FROM schema_migrations").Row().Scan(&version, &dirty); err != nil {
+		t.Fatal(err)
+	}
+	if want := latest[len(latest)-1].version; version != want || dirty {
+		t.Errorf("schema_migrations = %d (dirty %v), want %d", version, dirty, want)
+	}
+
+	var got struct {
+		Price    int64
+		Currency string
+		TenantID uuid.UUID
+	}
+	if err := db.Raw("SELECT price, currency, tenant_id FROM subscriptions WHERE id = ?", sub.ID).Scan(&got).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Price != 39900 {
+		t.Errorf("price = %d, want 39900 (rubles converted to kopecks once)", got.Price)
+	}
+	if got.Currency != "RUB" || got.TenantID != uuid.MustParse("00000000-0000-0000-0000-000000000001") {
+		t.Errorf("currency, tenant = %s, %s, want RUB in the default tenant", got.Currency, got.TenantID)
+	}
+
+	var constraints int64
+	if err := db.Raw(`SELECT count(*) FROM pg_constraint c JOIN pg_class r ON r.oid = c.conrelid
+		WHERE r.relname = 'subscriptions' AND r.relnamespace = current_schema()::regnamespace
+		AND c.conname = 'subscriptions_no_overlap'`).Scan(&constraints).Error; err != nil {
+		t.Fatal(err)
+	}
+	if constraints != 1 {
+		t.Error("subscriptions_no_overlap is missing after adoption")
+	}
+}
+
+func TestMigrateRejectsUnknownSchema(t *testing.T) {
+	db := testSchema(t)
+	if err := db.Exec("CREATE TABLE subscriptions (id uuid PRIMARY KEY, price bigint, currency char(3))").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	err := Migrate(context.Background(), db)
+	if err == nil || !strings.Contains(err.Error(), "do not match migration 0001") {
+		t.Fatalf("Migrate() error = %v, want a schema mismatch", err)
+	}
+	// версия не записана: после исправления схемы 0002 по-прежнему применится один раз
+	var exists bool
+	if err := db.Raw("SELECT to_regclass(current_schema() || '.schema_migrations') IS NOT NULL").Scan(&exists).Error; err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Error("schema_migrations was created for a rejected schema")
+	}
+}
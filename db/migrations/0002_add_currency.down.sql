@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS currency;
+UPDATE subscriptions SET price = price / 100;
+ALTER TABLE subscriptions ALTER COLUMN price TYPE integer;
@@ -0,0 +1,4 @@
+ALTER TABLE subscriptions ALTER COLUMN price TYPE bigint;
+-- цены хранились в целых рублях, переводим в копейки
+UPDATE subscriptions SET price = price * 100;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS currency char(3) NOT NULL DEFAULT 'RUB';
@@ -0,0 +1,4 @@
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_billing_count_check;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_billing_unit_check;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS billing_count;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS billing_unit;
@@ -0,0 +1,5 @@
+-- существующие подписки считаются ежемесячными
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS billing_unit varchar(10) NOT NULL DEFAULT 'month';
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS billing_count integer NOT NULL DEFAULT 1;
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_billing_unit_check CHECK (billing_unit IN ('week', 'month', 'year'));
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_billing_count_check CHECK (billing_count >= 1);
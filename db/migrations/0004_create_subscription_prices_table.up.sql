@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS subscription_prices (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subscription_id uuid NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    price bigint NOT NULL,
+    effective_from date NOT NULL,
+    created_at timestamp with time zone DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_prices_effective ON subscription_prices (subscription_id, effective_from);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_subscriptions_trial_end_date;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS trial_end_date;
@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS trial_end_date date NULL;
+CREATE INDEX IF NOT EXISTS idx_subscriptions_trial_end_date ON subscriptions (trial_end_date) WHERE trial_end_date IS NOT NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS subscription_pauses (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subscription_id uuid NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    start_date date NOT NULL,
+    end_date date NULL,
+    created_at timestamp with time zone DEFAULT now(),
+    CHECK (end_date IS NULL OR end_date > start_date)
+);
+CREATE INDEX IF NOT EXISTS idx_subscription_pauses_subscription_id ON subscription_pauses (subscription_id);
+-- у подписки может быть только одна незавершённая пауза
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_pauses_open ON subscription_pauses (subscription_id) WHERE end_date IS NULL;
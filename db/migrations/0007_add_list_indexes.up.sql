@@ -0,0 +1,7 @@
+-- индексы под keyset-пагинацию: сортировка всегда по (поле, id)
+CREATE INDEX IF NOT EXISTS idx_subscriptions_start_date_id ON subscriptions (start_date, id);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_price_id ON subscriptions (price, id);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_created_at_id ON subscriptions (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_service_name_id ON subscriptions (service_name, id);
+-- поиск по префиксу названия без учёта регистра
+CREATE INDEX IF NOT EXISTS idx_subscriptions_service_name_lower ON subscriptions (lower(service_name) text_pattern_ops);
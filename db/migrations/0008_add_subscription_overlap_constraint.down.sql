@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_no_overlap;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS active_period;
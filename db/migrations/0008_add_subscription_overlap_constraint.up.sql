@@ -0,0 +1,36 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- период действия подписки: месяц end_date оплачивается, поэтому верхняя граница — следующий месяц
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS active_period daterange
+    GENERATED ALWAYS AS (daterange(start_date, (end_date + interval '1 month')::date, '[)')) STORED;
+
+-- перед добавлением ограничения сообщаем обо всех пересекающихся подписках;
+-- если они есть, миграция прерывается, пока данные не будут исправлены вручную
+DO $$
+DECLARE
+    conflict record;
+    conflicts integer := 0;
+BEGIN
+    FOR conflict IN
+        SELECT a.id AS first_id, b.id AS second_id, a.user_id, a.service_name
+        FROM subscriptions a
+        JOIN subscriptions b
+          ON a.user_id = b.user_id
+         AND a.service_name = b.service_name
+         AND a.id < b.id
+         AND a.active_period && b.active_period
+        ORDER BY a.user_id, a.service_name
+    LOOP
+        conflicts := conflicts + 1;
+        RAISE WARNING 'overlapping subscriptions % and % (user %, service %)',
+            conflict.first_id, conflict.second_id, conflict.user_id, conflict.service_name;
+    END LOOP;
+
+    IF conflicts > 0 THEN
+        RAISE EXCEPTION '% overlapping subscription pair(s) found, resolve them before applying this migration', conflicts;
+    END IF;
+END
+$$;
+
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_no_overlap
+    EXCLUDE USING gist (user_id WITH =, service_name WITH =, active_period WITH &&);
@@ -0,0 +1,9 @@
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_no_overlap;
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_no_overlap
+    EXCLUDE USING gist (user_id WITH =, service_name WITH =, active_period WITH &&);
+
+DROP INDEX IF EXISTS idx_subscriptions_tenant_start_date_id;
+DROP INDEX IF EXISTS idx_subscriptions_tenant_user;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS fk_subscriptions_tenant;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS tenant_id;
+DROP TABLE IF EXISTS tenants;
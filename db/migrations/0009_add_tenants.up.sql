@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS tenants (
+    id uuid PRIMARY KEY,
+    name varchar(200) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenants_name ON tenants (name);
+
+-- существующие подписки переносятся в организацию по умолчанию
+INSERT INTO tenants (id, name) VALUES ('00000000-0000-0000-0000-000000000001', 'default')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS tenant_id uuid;
+UPDATE subscriptions SET tenant_id = '00000000-0000-0000-0000-000000000001' WHERE tenant_id IS NULL;
+ALTER TABLE subscriptions ALTER COLUMN tenant_id SET NOT NULL;
+ALTER TABLE subscriptions ADD CONSTRAINT fk_subscriptions_tenant
+    FOREIGN KEY (tenant_id) REFERENCES tenants (id);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_tenant_user ON subscriptions (tenant_id, user_id);
+-- сортировка списка по умолчанию внутри организации
+CREATE INDEX IF NOT EXISTS idx_subscriptions_tenant_start_date_id ON subscriptions (tenant_id, start_date, id);
+
+-- пересечение периодов проверяется внутри организации
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_no_overlap;
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_no_overlap
+    EXCLUDE USING gist (tenant_id WITH =, user_id WITH =, service_name WITH =, active_period WITH &&);
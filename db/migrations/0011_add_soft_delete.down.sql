@@ -0,0 +1,9 @@
+-- помеченные удалёнными подписки удаляются окончательно, как до мягкого удаления
+DELETE FROM subscriptions WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_no_overlap;
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_no_overlap
+    EXCLUDE USING gist (tenant_id WITH =, user_id WITH =, service_name WITH =, active_period WITH &&);
+
+DROP INDEX IF EXISTS idx_subscriptions_deleted_at;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,8 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_subscriptions_deleted_at ON subscriptions (deleted_at);
+
+-- удалённые подписки не участвуют в проверке пересечения периодов
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_no_overlap;
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_no_overlap
+    EXCLUDE USING gist (tenant_id WITH =, user_id WITH =, service_name WITH =, active_period WITH &&)
+    WHERE (deleted_at IS NULL);
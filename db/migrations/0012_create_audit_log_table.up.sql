@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id uuid PRIMARY KEY,
+    tenant_id uuid NOT NULL,
+    subscription_id uuid NOT NULL, -- без внешнего ключа: журнал переживает очистку подписки
+    actor varchar(200) NOT NULL,
+    request_id varchar(128),
+    action varchar(20) NOT NULL,
+    changes jsonb NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_tenant_created ON audit_log (tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_subscription_id ON audit_log (subscription_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor);
+
+-- журнал только пополняется: изменение и удаление записей запрещены
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    tenant_id uuid NOT NULL,
+    actor varchar(200) NOT NULL,
+    key varchar(255) NOT NULL,
+    fingerprint char(64) NOT NULL, -- SHA-256 метода, пути и тела запроса
+    status integer NOT NULL DEFAULT 0, -- 0, пока запрос выполняется
+    headers jsonb,
+    body bytea,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    expires_at timestamptz NOT NULL,
+    PRIMARY KEY (tenant_id, actor, key)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS calendar_tokens (
+    tenant_id uuid NOT NULL REFERENCES tenants (id),
+    user_id uuid NOT NULL,
+    token_hash char(64) NOT NULL, -- SHA-256 токена, сам токен не хранится
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, user_id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_tokens_token_hash ON calendar_tokens (token_hash);
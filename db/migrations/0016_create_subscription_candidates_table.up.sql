@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS subscription_candidates (
+    id uuid PRIMARY KEY,
+    tenant_id uuid NOT NULL REFERENCES tenants (id),
+    user_id uuid NOT NULL,
+    merchant_key varchar(200) NOT NULL, -- нормализованное имя получателя из выписки
+    service_name varchar(200) NOT NULL,
+    price bigint NOT NULL,
+    currency char(3) NOT NULL,
+    billing_unit varchar(10) NOT NULL,
+    billing_count integer NOT NULL,
+    start_date date NOT NULL,
+    end_date date,
+    last_charge_date date NOT NULL,
+    occurrences integer NOT NULL,
+    status varchar(10) NOT NULL DEFAULT 'pending', -- pending, accepted, dismissed
+    subscription_id uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_candidates_merchant
+    ON subscription_candidates (tenant_id, user_id, merchant_key, currency);
+CREATE INDEX IF NOT EXISTS idx_subscription_candidates_status ON subscription_candidates (status);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS reminder_logs;
+DROP TABLE IF EXISTS notification_preferences;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    tenant_id uuid NOT NULL REFERENCES tenants (id),
+    user_id uuid NOT NULL,
+    email varchar(254) NOT NULL,
+    renewal_reminders boolean NOT NULL DEFAULT true,
+    ending_reminders boolean NOT NULL DEFAULT true,
+    days_before integer NOT NULL DEFAULT 0, -- 0 — окно напоминаний по настройке сервера
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS reminder_logs (
+    subscription_id uuid NOT NULL,
+    kind varchar(10) NOT NULL, -- renewal, ending
+    due_date date NOT NULL,
+    tenant_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    sent_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (subscription_id, kind, due_date)
+);
+CREATE INDEX IF NOT EXISTS idx_reminder_logs_due_date ON reminder_logs (due_date);
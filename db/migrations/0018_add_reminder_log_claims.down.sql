@@ -0,0 +1,3 @@
+DELETE FROM reminder_logs WHERE sent_at IS NULL;
+ALTER TABLE reminder_logs ALTER COLUMN sent_at SET DEFAULT now(), ALTER COLUMN sent_at SET NOT NULL;
+ALTER TABLE reminder_logs DROP COLUMN IF EXISTS claimed_at;
@@ -0,0 +1,4 @@
+-- Отметка о напоминании ставится до отправки (claimed_at), а sent_at — только после
+-- доставки: отметку без sent_at, брошенную упавшим процессом, заберёт следующий запуск.
+ALTER TABLE reminder_logs ADD COLUMN IF NOT EXISTS claimed_at timestamptz NOT NULL DEFAULT now();
+ALTER TABLE reminder_logs ALTER COLUMN sent_at DROP NOT NULL, ALTER COLUMN sent_at DROP DEFAULT;
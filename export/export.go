@@ -0,0 +1,159 @@
+// Package export пишет табличные выгрузки в CSV, XLSX и NDJSON построчно,
+// не накапливая результат в памяти.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Форматы выгрузки.
+const (
+	CSV    = "csv"
+	XLSX   = "xlsx"
+	NDJSON = "ndjson"
+)
+
+var contentTypes = map[string]string{
+	CSV:    "text/csv; charset=utf-8",
+	XLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	NDJSON: "application/x-ndjson",
+}
+
+// Valid сообщает, поддерживается ли формат.
+func Valid(format string) bool {
+	_, ok := contentTypes[format]
+	return ok
+}
+
+// ContentType возвращает MIME-тип формата.
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// FromMediaType возвращает формат для MIME-типа из заголовка Accept или пустую строку.
+func FromMediaType(mediaType string) string {
+	switch strings.ToLower(mediaType) {
+	case "text/csv":
+		return CSV
+	case "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":
+		return XLSX
+	case "application/x-ndjson", "application/jsonl":
+		return NDJSON
+	}
+	return ""
+}
+
+// Writer пишет строки таблицы. Значения строки идут в порядке столбцов и могут быть
+// nil, строками, целыми или дробными числами, в CSV и XLSX прочие типы пишутся через fmt.
+type Writer interface {
+	WriteRow(values []any) error
+	// Flush отправляет записанные строки в нижележащий io.Writer.
+	Flush() error
+	// Close дописывает выгрузку; без него XLSX получится повреждённым.
+	Close() error
+}
+
+// NewWriter создаёт Writer формата format и сразу пишет заголовок из columns.
+func NewWriter(format string, w io.Writer, columns []string) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w, columns)
+	case XLSX:
+		return newXLSXWriter(w, columns)
+	case NDJSON:
+		return &ndjsonWriter{w: bufio.NewWriter(w), columns: columns}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	// BOM нужен Excel, чтобы открыть файл в UTF-8, а не в системной кодировке
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	return cw, cw.w.Write(columns)
+}
+
+func (cw *csvWriter) WriteRow(values []any) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			record[i] = escapeFormula(v)
+		default:
+			record[i] = formatValue(v)
+		}
+	}
+	return cw.w.Write(record)
+}
+
+func (cw *csvWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) Close() error { return cw.Flush() }
+
+// escapeFormula не даёт табличному редактору выполнить строку из данных как формулу.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// ndjsonWriter пишет каждую строку JSON-объектом с ключами-столбцами в их порядке.
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	columns []string
+}
+
+func (nw *ndjsonWriter) WriteRow(values []any) error {
+	nw.w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			nw.w.WriteByte(',')
+		}
+		key, _ := json.Marshal(nw.columns[i])
+		val, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		nw.w.Write(key)
+		nw.w.WriteByte(':')
+		nw.w.Write(val)
+	}
+	nw.w.WriteString("}\n")
+	return nil
+}
+
+func (nw *ndjsonWriter) Flush() error { return nw.w.Flush() }
+
+func (nw *ndjsonWriter) Close() error { return nw.Flush() }
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
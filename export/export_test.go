@@ -0,0 +1,113 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(CSV, &buf, []string{"name", "note", "price", "rate", "end"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]any{
+		{"=SUM(A1:A2)", "+1", int64(-500), -1.5, nil},
+		{"-2", "@cmd", 0, 2.25, "2024-01"},
+		{"\tx", "a,\"b\"", int64(1), 0.5, ""},
+		{"Netflix", "1-2", int64(1549), 3.0, "01-2025"},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "\ufeff") {
+		t.Fatalf("output does not start with a BOM: %q", out)
+	}
+	got, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(out, "\ufeff"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"name", "note", "price", "rate", "end"},
+		// строки, начинающиеся с = + - @ или табуляции, экранируются, числа — нет
+		{"'=SUM(A1:A2)", "'+1", "-500", "-1.5", ""},
+		{"'-2", "'@cmd", "0", "2.25", "2024-01"},
+		{"'\tx", "a,\"b\"", "1", "0.5", ""},
+		{"Netflix", "1-2", "1549", "3", "01-2025"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(NDJSON, &buf, []string{"id", "name", "price", "end"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow([]any{"1", `say "hi"`, int64(1549), nil}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow([]any{"2", "=cmd", 1.5, "01-2025"}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("rows written before Flush: %q", buf.String())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// ключи идут в порядке столбцов, строки не экранируются как формулы
+	want := `{"id":"1","name":"say \"hi\"","price":1549,"end":null}` + "\n" +
+		`{"id":"2","name":"=cmd","price":1.5,"end":"01-2025"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("invalid JSON line %q", line)
+		}
+	}
+}
+
+func TestNewWriterUnknownFormat(t *testing.T) {
+	if _, err := NewWriter("pdf", &bytes.Buffer{}, nil); err == nil {
+		t.Error("NewWriter(pdf) error = nil, want error")
+	}
+}
+
+func TestFromMediaType(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		want      string
+	}{
+		{"text/csv", CSV},
+		{"TEXT/CSV", CSV},
+		{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", XLSX},
+		{"application/x-ndjson", NDJSON},
+		{"application/jsonl", NDJSON},
+		{"application/json", ""},
+		{"*/*", ""},
+	}
+	for _, tt := range tests {
+		if got := FromMediaType(tt.mediaType); got != tt.want {
+			t.Errorf("FromMediaType(%q) = %q, want %q", tt.mediaType, got, tt.want)
+		}
+		if tt.want != "" && !Valid(tt.want) {
+			t.Errorf("Valid(%q) = false", tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// xlsxSheet — лист книги в объёме, который пишет xlsxWriter.
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			T  string `xml:"t,attr"`
+			V  string `xml:"v"`
+			Is *struct {
+				T string `xml:"t"`
+			} `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(XLSX, &buf, []string{"name", "price", "rate", "end"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]any{
+		{`Tom & Jerry <"HD">`, int64(1549), 1.5, nil},
+		{"=SUM(A1:A2)", -3, 0.25, "  01-2025 "},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = string(data)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		body, ok := parts[name]
+		if !ok {
+			t.Errorf("missing part %s", name)
+			continue
+		}
+		if err := xml.Unmarshal([]byte(body), new(struct{})); err != nil {
+			t.Errorf("%s is not well-formed XML: %v", name, err)
+		}
+	}
+	// таблицы общих строк в книге нет, поэтому все строки должны быть встроенными
+	if _, ok := parts["xl/sharedStrings.xml"]; ok {
+		t.Error("unexpected xl/sharedStrings.xml")
+	}
+
+	raw := parts["xl/worksheets/sheet1.xml"]
+	if !strings.Contains(raw, "Tom &amp; Jerry &lt;&#34;HD&#34;&gt;") {
+		t.Errorf("special characters are not escaped:\n%s", raw)
+	}
+	if strings.Contains(raw, `t="s"`) {
+		t.Errorf("sheet refers to shared strings:\n%s", raw)
+	}
+
+	var sheet xlsxSheet
+	if err := xml.Unmarshal([]byte(raw), &sheet); err != nil {
+		t.Fatal(err)
+	}
+	type cell struct{ t, v string }
+	var got [][]cell
+	for i, row := range sheet.Rows {
+		if row.R != i+1 {
+			t.Errorf("row %d has r=%d", i+1, row.R)
+		}
+		var cells []cell
+		for _, c := range row.Cells {
+			v := c.V
+			if c.Is != nil {
+				v = c.Is.T
+			}
+			cells = append(cells, cell{c.T, v})
+		}
+		got = append(got, cells)
+	}
+	want := [][]cell{
+		{{"inlineStr", "name"}, {"inlineStr", "price"}, {"inlineStr", "rate"}, {"inlineStr", "end"}},
+		{{"inlineStr", `Tom & Jerry <"HD">`}, {"", "1549"}, {"", "1.5"}, {"", ""}},
+		// в XLSX строка остаётся текстом и формулой не станет, экранировать её не нужно
+		{{"inlineStr", "=SUM(A1:A2)"}, {"", "-3"}, {"", "0.25"}, {"inlineStr", "  01-2025 "}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+// Package ical пишет календари iCalendar (RFC 5545) с повторяющимися событиями на весь день.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType — MIME-тип календаря.
+const ContentType = "text/calendar; charset=utf-8"
+
+// Частоты повторения.
+const (
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// maxLineOctets — предельная длина строки без переноса (RFC 5545, 3.1).
+const maxLineOctets = 75
+
+// Recurrence — правило повторения RRULE.
+type Recurrence struct {
+	Freq     string
+	Interval int
+	Until    *time.Time // последняя дата включительно; nil — бессрочно
+}
+
+// Event — событие на весь день Start, повторяющееся по Recurrence.
+type Event struct {
+	UID         string
+	Sequence    int64 // растёт при изменении события, чтобы клиенты обновили копию
+	Start       time.Time
+	Summary     string
+	Description string
+	Recurrence  *Recurrence
+	Except      []time.Time // пропускаемые повторения (EXDATE)
+}
+
+// Calendar — календарь с событиями; Name показывается клиентами как имя подписки.
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Write пишет календарь в w. stamp — время формирования (DTSTAMP).
+func (cal *Calendar) Write(w io.Writer, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) { writeLine(bw, s) }
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:" + cal.ProdID)
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	if cal.Name != "" {
+		line("X-WR-CALNAME:" + escapeText(cal.Name))
+	}
+	for _, e := range cal.Events {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID)
+		line("SEQUENCE:" + strconv.FormatInt(e.Sequence, 10))
+		line("DTSTAMP:" + stamp.UTC().Format("20060102T150405Z"))
+		line("DTSTART;VALUE=DATE:" + formatDate(e.Start))
+		line("DTEND;VALUE=DATE:" + formatDate(e.Start.AddDate(0, 0, 1)))
+		line("SUMMARY:" + escapeText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:" + escapeText(e.Description))
+		}
+		if r := e.Recurrence; r != nil {
+			rule := "RRULE:FREQ=" + r.Freq + ";INTERVAL=" + strconv.Itoa(max(r.Interval, 1))
+			if r.Until != nil {
+				rule += ";UNTIL=" + formatDate(*r.Until)
+			}
+			line(rule)
+		}
+		if len(e.Except) > 0 {
+			dates := make([]string, len(e.Except))
+			for i, d := range e.Except {
+				dates[i] = formatDate(d)
+			}
+			line("EXDATE;VALUE=DATE:" + strings.Join(dates, ","))
+		}
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("20060102")
+}
+
+// escapeText экранирует значение типа TEXT (RFC 5545, 3.3.11).
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeLine пишет строку содержимого с CRLF, перенося её по maxLineOctets октетов
+// без разрыва символов UTF-8.
+func writeLine(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxLineOctets - 1 // продолжение начинается с пробела
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKey — ключ доступа для сервисных клиентов. Хранится только хеш секрета.
+type APIKey struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey;" json:"id"`
+	TenantID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	Name       string     `gorm:"type:varchar(200);not null" json:"name"`
+	SecretHash string     `gorm:"type:char(64);not null" json:"-"`  // SHA-256 секрета, hex
+	Scopes     string     `gorm:"type:text;not null" json:"scopes"` // разрешения через пробел
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedBy  *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+}
+
+func (k *APIKey) BeforeCreate(tx *gorm.DB) (err error) {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return
+}
+
+// Active сообщает, можно ли аутентифицироваться ключом в момент now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
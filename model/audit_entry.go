@@ -0,0 +1,41 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Действия, которые записываются в журнал аудита.
+const (
+	AuditCreate      = "create"
+	AuditUpdate      = "update"
+	AuditDelete      = "delete"
+	AuditRestore     = "restore"
+	AuditPriceChange = "price_change"
+	AuditPause       = "pause"
+	AuditResume      = "resume"
+)
+
+// AuditEntry — запись журнала изменений подписки. Журнал только пополняется.
+type AuditEntry struct {
+	ID             uuid.UUID       `gorm:"type:uuid;primaryKey;" json:"id"`
+	TenantID       uuid.UUID       `gorm:"type:uuid;not null;index:idx_audit_log_tenant_created,priority:1" json:"tenant_id"`
+	SubscriptionID uuid.UUID       `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	Actor          string          `gorm:"type:varchar(200);not null;index" json:"actor"` // sub токена или apikey:<id>
+	RequestID      string          `gorm:"type:varchar(128)" json:"request_id,omitempty"`
+	Action         string          `gorm:"type:varchar(20);not null" json:"action"`
+	Changes        json.RawMessage `gorm:"type:jsonb;not null" json:"changes" swaggertype:"object"` // {"поле": {"before": ..., "after": ...}}
+	CreatedAt      time.Time       `gorm:"autoCreateTime;index:idx_audit_log_tenant_created,priority:2" json:"created_at"`
+}
+
+func (AuditEntry) TableName() string { return "audit_log" }
+
+func (e *AuditEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CalendarToken — токен ленты продлений пользователя в календаре. У пользователя
+// один токен; хранится только его хеш.
+type CalendarToken struct {
+	TenantID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TokenHash string    `gorm:"type:char(64);not null;uniqueIndex"` // SHA-256 токена, hex
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey — сохранённый ответ на запрос с заголовком Idempotency-Key.
+// Ключ действует в пределах организации и вызывающего.
+type IdempotencyKey struct {
+	TenantID    uuid.UUID       `gorm:"type:uuid;primaryKey"`
+	Actor       string          `gorm:"type:varchar(200);primaryKey"` // sub токена или apikey:<id>
+	Key         string          `gorm:"type:varchar(255);primaryKey"`
+	Fingerprint string          `gorm:"type:char(64);not null"` // SHA-256 метода, пути и тела запроса, hex
+	Status      int             `gorm:"not null;default:0"`     // 0 — запрос ещё выполняется
+	Headers     json.RawMessage `gorm:"type:jsonb"`             // сохранённые заголовки ответа
+	Body        []byte          `gorm:"type:bytea"`
+	CreatedAt   time.Time       `gorm:"autoCreateTime"`
+	ExpiresAt   time.Time       `gorm:"not null;index"`
+}
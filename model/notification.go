@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Виды напоминаний.
+const (
+	ReminderRenewal = "renewal" // скоро очередное списание
+	ReminderEnding  = "ending"  // подписка заканчивается (EndDate)
+)
+
+// NotificationPreference — настройки напоминаний пользователя. Без них напоминания
+// пользователю не отправляются: сервису неоткуда взять его адрес.
+type NotificationPreference struct {
+	TenantID         uuid.UUID `gorm:"type:uuid;primaryKey" json:"tenant_id"`
+	UserID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	Email            string    `gorm:"type:varchar(254);not null" json:"email"`
+	RenewalReminders bool      `gorm:"not null" json:"renewal_reminders"` // без default: иначе gorm не записывает false
+	EndingReminders  bool      `gorm:"not null" json:"ending_reminders"`
+	DaysBefore       int       `gorm:"not null;default:0" json:"days_before"` // за сколько дней напоминать; 0 — по настройке сервера
+	UpdatedAt        time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// ReminderLog — отметка о напоминании. Одно событие подписки (вид и дата)
+// напоминается один раз, даже если сервер запущен в нескольких экземплярах.
+type ReminderLog struct {
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	Kind           string     `gorm:"type:varchar(10);primaryKey"`
+	DueDate        time.Time  `gorm:"type:date;primaryKey;index"` // дата списания или окончания подписки
+	TenantID       uuid.UUID  `gorm:"type:uuid;not null"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null"`
+	ClaimedAt      time.Time  `gorm:"not null"` // когда экземпляр сервера взялся отправить напоминание
+	SentAt         *time.Time // nil — доставка ещё не подтверждена
+}
@@ -7,19 +7,73 @@ import (
 	"gorm.io/gorm"
 )
 
+// Единицы периода списания.
+const (
+	BillingWeek  = "week"
+	BillingMonth = "month"
+	BillingYear  = "year"
+)
+
 type Subscription struct {
-	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;" json:"id"`
-	ServiceName string     `gorm:"type:varchar(200);not null;index" json:"service_name"`
-	Price       int        `gorm:"not null" json:"price"`
-	UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
-	StartDate   time.Time  `gorm:"type:date;not null" json:"start_date"`
-	EndDate     *time.Time `gorm:"type:date" json:"end_date,omitempty"`
-	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	ID           uuid.UUID      `gorm:"type:uuid;primaryKey;" json:"id"`
+	ServiceName  string         `gorm:"type:varchar(200);not null;index" json:"service_name"`
+	Price        int64          `gorm:"not null" json:"price"`  // начальная цена в минимальных единицах валюты (копейки, центы)
+	CurrentPrice int64          `gorm:"-" json:"current_price"` // цена, действующая в текущем месяце, с учётом price_history
+	Currency     string         `gorm:"type:char(3);not null;default:'RUB'" json:"currency"`
+	TenantID     uuid.UUID      `gorm:"type:uuid;not null;index:idx_subscriptions_tenant_user,priority:1" json:"tenant_id"`
+	UserID       uuid.UUID      `gorm:"type:uuid;not null;index;index:idx_subscriptions_tenant_user,priority:2" json:"user_id"`
+	StartDate    time.Time      `gorm:"type:date;not null" json:"start_date"`
+	EndDate      *time.Time     `gorm:"type:date" json:"end_date,omitempty"`
+	BillingUnit  string         `gorm:"type:varchar(10);not null;default:'month'" json:"billing_unit"` // week, month, year
+	BillingCount int            `gorm:"not null;default:1" json:"billing_count"`                       // списание раз в BillingCount единиц
+	TrialEndDate *time.Time     `gorm:"type:date;index" json:"trial_end_date,omitempty"`               // первый платный месяц; раньше — пробный период
+	Version      int64          `gorm:"not null;default:1" json:"version"`                             // растёт при каждом изменении подписки; отдаётся как ETag
+	CreatedAt    time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string" format:"date-time"` // мягкое удаление; такие подписки не видны в запросах
+
+	Prices []SubscriptionPrice `gorm:"foreignKey:SubscriptionID;constraint:OnDelete:CASCADE" json:"price_history,omitempty"`
+	Pauses []SubscriptionPause `gorm:"foreignKey:SubscriptionID;constraint:OnDelete:CASCADE" json:"pauses,omitempty"`
 }
 
 func (s *Subscription) BeforeCreate(tx *gorm.DB) (err error) {
 	if s.ID == uuid.Nil {
 		s.ID = uuid.New()
 	}
+	if s.Version == 0 {
+		s.Version = 1
+	}
+	return
+}
+
+// AfterFind и AfterSave заполняют CurrentPrice по загруженной истории цен.
+func (s *Subscription) AfterFind(tx *gorm.DB) (err error) {
+	s.CurrentPrice = s.PriceAt(time.Now().UTC())
+	return
+}
+
+func (s *Subscription) AfterSave(tx *gorm.DB) (err error) {
+	s.CurrentPrice = s.PriceAt(time.Now().UTC())
 	return
 }
+
+// OpenPause возвращает текущую незавершённую паузу или nil.
+func (s *Subscription) OpenPause() *SubscriptionPause {
+	for i := range s.Pauses {
+		if s.Pauses[i].EndDate == nil {
+			return &s.Pauses[i]
+		}
+	}
+	return nil
+}
+
+// PriceAt возвращает цену, действующую на дату t. Prices должны быть отсортированы по EffectiveFrom.
+func (s *Subscription) PriceAt(t time.Time) int64 {
+	price := s.Price
+	for _, p := range s.Prices {
+		if p.EffectiveFrom.After(t) {
+			break
+		}
+		price = p.Price
+	}
+	return price
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Состояния кандидата в подписки.
+const (
+	CandidatePending   = "pending"
+	CandidateAccepted  = "accepted"
+	CandidateDismissed = "dismissed"
+)
+
+// SubscriptionCandidate — регулярное списание из банковской выписки, которое может
+// оказаться подпиской. Пользователь принимает кандидата (создаётся подписка) или
+// отклоняет его; решённые кандидаты при повторном импорте не предлагаются.
+type SubscriptionCandidate struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey;" json:"id"`
+	TenantID       uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_subscription_candidates_merchant,priority:1" json:"tenant_id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_subscription_candidates_merchant,priority:2" json:"user_id"`
+	MerchantKey    string     `gorm:"type:varchar(200);not null;uniqueIndex:idx_subscription_candidates_merchant,priority:3" json:"-"` // нормализованное имя получателя
+	ServiceName    string     `gorm:"type:varchar(200);not null" json:"service_name"`
+	Price          int64      `gorm:"not null" json:"price"` // сумма последнего списания в минимальных единицах валюты
+	Currency       string     `gorm:"type:char(3);not null;uniqueIndex:idx_subscription_candidates_merchant,priority:4" json:"currency"`
+	BillingUnit    string     `gorm:"type:varchar(10);not null" json:"billing_unit"`
+	BillingCount   int        `gorm:"not null" json:"billing_count"`
+	StartDate      time.Time  `gorm:"type:date;not null" json:"start_date"` // месяц первого списания в выписке
+	EndDate        *time.Time `gorm:"type:date" json:"end_date,omitempty"`  // месяц последнего списания, если подписку, похоже, отменили
+	LastChargeDate time.Time  `gorm:"type:date;not null" json:"last_charge_date"`
+	Occurrences    int        `gorm:"not null" json:"occurrences"` // сколько списаний найдено
+	Status         string     `gorm:"type:varchar(10);not null;default:'pending';index" json:"status"`
+	SubscriptionID *uuid.UUID `gorm:"type:uuid" json:"subscription_id,omitempty"` // подписка, созданная при принятии
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (c *SubscriptionCandidate) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SubscriptionPause — приостановка подписки с месяца StartDate до месяца EndDate (не включительно).
+// EndDate == nil — подписка приостановлена до сих пор.
+type SubscriptionPause struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey;" json:"id"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	StartDate      time.Time  `gorm:"type:date;not null" json:"start_date"`
+	EndDate        *time.Time `gorm:"type:date" json:"end_date,omitempty"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (p *SubscriptionPause) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SubscriptionPrice — изменение цены подписки, действующее с месяца EffectiveFrom
+// до следующего изменения. До первого изменения действует Subscription.Price.
+type SubscriptionPrice struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey;" json:"id"`
+	SubscriptionID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_subscription_prices_effective" json:"subscription_id"`
+	Price          int64     `gorm:"not null" json:"price"`
+	EffectiveFrom  time.Time `gorm:"type:date;not null;uniqueIndex:idx_subscription_prices_effective" json:"effective_from"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (p *SubscriptionPrice) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return
+}
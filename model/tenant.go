@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Tenant — организация (бизнес-подразделение); данные разных организаций изолированы.
+type Tenant struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;" json:"id"`
+	Name      string    `gorm:"type:varchar(200);not null;uniqueIndex" json:"name"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (t *Tenant) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
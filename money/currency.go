@@ -0,0 +1,50 @@
+package money
+
+import (
+	"strconv"
+	"strings"
+)
+
+// exponents — количество знаков минимальной единицы для кодов ISO 4217.
+var exponents = map[string]int{
+	"AED": 2, "AMD": 2, "AUD": 2, "AZN": 2, "BGN": 2, "BHD": 3, "BRL": 2,
+	"BYN": 2, "CAD": 2, "CHF": 2, "CNY": 2, "CZK": 2, "DKK": 2, "EUR": 2,
+	"GBP": 2, "GEL": 2, "HKD": 2, "HUF": 2, "ILS": 2, "INR": 2, "IQD": 3,
+	"JOD": 3, "JPY": 0, "KGS": 2, "KRW": 0, "KWD": 3, "KZT": 2, "LYD": 3,
+	"MDL": 2, "MXN": 2, "NOK": 2, "NZD": 2, "OMR": 3, "PLN": 2, "RON": 2,
+	"RSD": 2, "RUB": 2, "SEK": 2, "SGD": 2, "THB": 2, "TJS": 2, "TND": 3,
+	"TRY": 2, "UAH": 2, "USD": 2, "UZS": 2, "VND": 0, "ZAR": 2,
+}
+
+// Normalize приводит код валюты к верхнему регистру.
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Valid сообщает, известен ли код валюты.
+func Valid(code string) bool {
+	_, ok := exponents[code]
+	return ok
+}
+
+// Exponent возвращает количество знаков после запятой для валюты.
+func Exponent(code string) int {
+	return exponents[code]
+}
+
+// Format переводит сумму из минимальных единиц валюты в десятичную запись: 99900 RUB -> "999.00 RUB".
+func Format(amount int64, currency string) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	exp := Exponent(currency)
+	s := strconv.FormatInt(amount, 10)
+	if exp > 0 {
+		if len(s) <= exp {
+			s = strings.Repeat("0", exp-len(s)+1) + s
+		}
+		s = s[:len(s)-exp] + "." + s[len(s)-exp:]
+	}
+	return sign + s + " " + currency
+}
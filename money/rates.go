@@ -0,0 +1,108 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// RateProvider отдаёт курс обмена: сколько единиц валюты to стоит одна единица валюты from.
+type RateProvider interface {
+	Rate(from, to string) (*big.Rat, error)
+}
+
+// FileRateProvider — таблица курсов, загружаемая из JSON-файла при старте.
+//
+// Формат файла:
+//
+//	{"base": "RUB", "rates": {"USD": "92.5", "EUR": "100.1"}}
+//
+// где каждое значение — стоимость одной единицы валюты в базовой валюте.
+type FileRateProvider struct {
+	base  string
+	rates map[string]*big.Rat
+}
+
+type rateFile struct {
+	Base  string                 `json:"base"`
+	Rates map[string]json.Number `json:"rates"`
+}
+
+func LoadRatesFile(path string) (*FileRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f rateFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse rates file %s: %w", path, err)
+	}
+
+	base := Normalize(f.Base)
+	if !Valid(base) {
+		return nil, fmt.Errorf("rates file %s: unknown base currency %q", path, f.Base)
+	}
+
+	p := &FileRateProvider{base: base, rates: map[string]*big.Rat{base: big.NewRat(1, 1)}}
+	for code, v := range f.Rates {
+		code = Normalize(code)
+		if !Valid(code) {
+			return nil, fmt.Errorf("rates file %s: unknown currency %q", path, code)
+		}
+		r, ok := new(big.Rat).SetString(v.String())
+		if !ok || r.Sign() <= 0 {
+			return nil, fmt.Errorf("rates file %s: invalid rate for %s", path, code)
+		}
+		p.rates[code] = r
+	}
+	return p, nil
+}
+
+func (p *FileRateProvider) Rate(from, to string) (*big.Rat, error) {
+	fr, ok := p.rates[from]
+	if !ok {
+		return nil, fmt.Errorf("no exchange rate for %s", from)
+	}
+	tr, ok := p.rates[to]
+	if !ok {
+		return nil, fmt.Errorf("no exchange rate for %s", to)
+	}
+	return new(big.Rat).Quo(fr, tr), nil
+}
+
+// Convert переводит сумму в минимальных единицах валюты from в минимальные единицы валюты to.
+// Результат округляется до ближайшего целого, половины — от нуля.
+func Convert(amount int64, from, to string, p RateProvider) (int64, error) {
+	if from == to {
+		return amount, nil
+	}
+	rate, err := p.Rate(from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	v := new(big.Rat).SetInt64(amount)
+	v.Mul(v, rate)
+	v.Mul(v, pow10(Exponent(to)))
+	v.Quo(v, pow10(Exponent(from)))
+
+	return roundRat(v), nil
+}
+
+func pow10(n int) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil))
+}
+
+func roundRat(v *big.Rat) int64 {
+	num := new(big.Int).Abs(v.Num())
+	q, r := new(big.Int).QuoRem(num, v.Denom(), new(big.Int))
+	if new(big.Int).Mul(r, big.NewInt(2)).Cmp(v.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if v.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
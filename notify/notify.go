@@ -0,0 +1,17 @@
+// Package notify отправляет уведомления пользователям. Notifier скрывает канал
+// доставки; первая реализация — электронная почта через SMTP.
+package notify
+
+import "context"
+
+// Message — уведомление одному получателю.
+type Message struct {
+	To      string // адрес получателя в формате канала, для SMTP — e-mail
+	Subject string
+	Body    string // простой текст
+}
+
+// Notifier доставляет уведомления.
+type Notifier interface {
+	Send(ctx context.Context, m Message) error
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/model"
+)
+
+// lastUsedPrecision — как часто обновляется время последнего использования ключа,
+// чтобы каждый запрос не превращался в запись в базу.
+const lastUsedPrecision = time.Minute
+
+type APIKeyRepo struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepo(db *gorm.DB) *APIKeyRepo { return &APIKeyRepo{db: db} }
+
+// Create создаёт ключ в организации из ctx.
+func (r *APIKeyRepo) Create(ctx context.Context, k *model.APIKey) error {
+	tenantID, ok := auth.TenantID(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+	k.TenantID = tenantID
+	return r.db.WithContext(ctx).Create(k).Error
+}
+
+// List возвращает ключи организации из ctx, новые первыми.
+func (r *APIKeyRepo) List(ctx context.Context) ([]*model.APIKey, error) {
+	var keys []*model.APIKey
+	if err := r.db.WithContext(ctx).Scopes(tenantScoped(ctx, "api_keys")).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.APIKey, error) {
+	var k model.APIKey
+	if err := r.db.WithContext(ctx).Scopes(tenantScoped(ctx, "api_keys")).First(&k, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &k, nil
+}
+
+// Update сохраняет ключ в пределах организации из ctx.
+func (r *APIKeyRepo) Update(ctx context.Context, k *model.APIKey) error {
+	res := r.db.WithContext(ctx).Model(k).Scopes(tenantScoped(ctx, "api_keys")).
+		Select("secret_hash", "revoked_at", "rotated_at").Updates(k)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Lookup находит ключ по ID для аутентификации, когда организация ещё не известна.
+func (r *APIKeyRepo) Lookup(ctx context.Context, id uuid.UUID) (*model.APIKey, error) {
+	var k model.APIKey
+	if err := r.db.WithContext(ctx).First(&k, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &k, nil
+}
+
+// Touch отмечает использование ключа с точностью до lastUsedPrecision.
+func (r *APIKeyRepo) Touch(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, at.Add(-lastUsedPrecision)).
+		Update("last_used_at", at).Error
+}
@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/model"
+	"subscriptions-go/requestid"
+)
+
+// fieldChange — значение поля до и после изменения; null опускается.
+type fieldChange struct {
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// writeAudit записывает изменение подписки в журнал в транзакции tx.
+// before и after — состояние записи до и после изменения, nil при создании и удалении.
+// Изменение, которое ничего не поменяло, не записывается.
+func writeAudit(ctx context.Context, tx *gorm.DB, tenantID, subscriptionID uuid.UUID, action string, before, after interface{}) error {
+	d := diff(snapshot(before), snapshot(after))
+	if len(d) == 0 {
+		return nil
+	}
+	changes, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&model.AuditEntry{
+		TenantID:       tenantID,
+		SubscriptionID: subscriptionID,
+		Actor:          auth.Actor(ctx),
+		RequestID:      requestid.From(ctx),
+		Action:         action,
+		Changes:        changes,
+	}).Error
+}
+
+// snapshot представляет запись как JSON-объект без вложенной истории цен и пауз.
+func snapshot(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	// UseNumber сохраняет суммы в минимальных единицах без потери точности
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return nil
+	}
+	delete(m, "price_history")
+	delete(m, "pauses")
+	return m
+}
+
+// diff возвращает изменившиеся поля.
+func diff(before, after map[string]interface{}) map[string]fieldChange {
+	changes := make(map[string]fieldChange)
+	for k, b := range before {
+		if a, ok := after[k]; !ok || !reflect.DeepEqual(a, b) {
+			changes[k] = fieldChange{Before: b, After: after[k]}
+		}
+	}
+	for k, a := range after {
+		if _, ok := before[k]; !ok {
+			changes[k] = fieldChange{After: a}
+		}
+	}
+	return changes
+}
+
+// AuditFilter — фильтры журнала аудита; записи идут от новых к старым.
+type AuditFilter struct {
+	SubscriptionID *uuid.UUID
+	Actor          *string
+	Action         *string
+	RequestID      *string
+	From           *time.Time // включительно
+	To             *time.Time // не включительно
+	Limit          int
+	Cursor         string
+}
+
+// AuditPage — страница журнала; NextCursor пуст на последней странице.
+type AuditPage struct {
+	Items      []*model.AuditEntry `json:"items"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+type AuditRepo struct {
+	db *gorm.DB
+}
+
+func NewAuditRepo(db *gorm.DB) *AuditRepo { return &AuditRepo{db: db} }
+
+// History возвращает журнал изменений подписки, доступной вызывающему (в том числе удалённой),
+// в хронологическом порядке.
+func (r *AuditRepo) History(ctx context.Context, subscriptionID uuid.UUID) ([]*model.AuditEntry, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&model.Subscription{}).Scopes(scoped(ctx)).
+		Where("id = ?", subscriptionID).Count(&n).Error
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrNotFound
+	}
+
+	var entries []*model.AuditEntry
+	err = r.db.WithContext(ctx).Scopes(tenantScoped(ctx, "audit_log")).
+		Where("subscription_id = ?", subscriptionID).
+		Order("created_at, id").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Search возвращает страницу журнала организации по фильтру.
+func (r *AuditRepo) Search(ctx context.Context, f AuditFilter) (*AuditPage, error) {
+	if f.Limit <= 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit > MaxPageSize {
+		f.Limit = MaxPageSize
+	}
+
+	db := r.db.WithContext(ctx).Scopes(tenantScoped(ctx, "audit_log"))
+	if f.SubscriptionID != nil {
+		db = db.Where("subscription_id = ?", *f.SubscriptionID)
+	}
+	if f.Actor != nil {
+		db = db.Where("actor = ?", *f.Actor)
+	}
+	if f.Action != nil {
+		db = db.Where("action = ?", *f.Action)
+	}
+	if f.RequestID != nil {
+		db = db.Where("request_id = ?", *f.RequestID)
+	}
+	if f.From != nil {
+		db = db.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		db = db.Where("created_at < ?", *f.To)
+	}
+	if f.Cursor != "" {
+		at, id, err := decodeAuditCursor(f.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("(created_at, id) < (?, ?)", at, id)
+	}
+
+	var entries []*model.AuditEntry
+	if err := db.Order("created_at DESC, id DESC").Limit(f.Limit + 1).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	page := &AuditPage{Items: entries}
+	if len(entries) > f.Limit {
+		page.Items = entries[:f.Limit]
+		last := page.Items[f.Limit-1]
+		page.NextCursor = encodeAuditCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
+
+func encodeAuditCursor(at time.Time, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(at.UTC().Format(time.RFC3339Nano) + "|" + id.String()))
+}
+
+func decodeAuditCursor(s string) (time.Time, uuid.UUID, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	ts, idStr, ok := strings.Cut(string(data), "|")
+	at, err1 := time.Parse(time.RFC3339Nano, ts)
+	id, err2 := uuid.Parse(idStr)
+	if !ok || err1 != nil || err2 != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	return at, id, nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/model"
+)
+
+type CalendarTokenRepo struct {
+	db *gorm.DB
+}
+
+func NewCalendarTokenRepo(db *gorm.DB) *CalendarTokenRepo { return &CalendarTokenRepo{db: db} }
+
+// Save сохраняет токен пользователя в организации из ctx, заменяя прежний.
+func (r *CalendarTokenRepo) Save(ctx context.Context, t *model.CalendarToken) error {
+	tenantID, ok := auth.TenantID(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+	t.TenantID = tenantID
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token_hash", "created_at"}),
+	}).Create(t).Error
+}
+
+// Delete удаляет токен пользователя в организации из ctx.
+func (r *CalendarTokenRepo) Delete(ctx context.Context, userID uuid.UUID) error {
+	res := r.db.WithContext(ctx).Scopes(tenantScoped(ctx, "calendar_tokens")).
+		Where("user_id = ?", userID).Delete(&model.CalendarToken{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Lookup находит токен по хешу, когда организация ещё не известна.
+func (r *CalendarTokenRepo) Lookup(ctx context.Context, hash string) (*model.CalendarToken, error) {
+	var t model.CalendarToken
+	if err := r.db.WithContext(ctx).First(&t, "token_hash = ?", hash).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &t, nil
+}
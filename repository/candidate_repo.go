@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/model"
+)
+
+// ErrAlreadyDecided возвращается, если кандидата уже приняли или отклонили.
+var ErrAlreadyDecided = errors.New("candidate has already been decided")
+
+type CandidateRepo struct {
+	db *gorm.DB
+}
+
+func NewCandidateRepo(db *gorm.DB) *CandidateRepo { return &CandidateRepo{db: db} }
+
+// Transaction выполняет fn в транзакции; репозитории кандидатов и подписок,
+// переданные в fn, работают внутри неё.
+func (r *CandidateRepo) Transaction(ctx context.Context, fn func(tx *CandidateRepo, subs *SubscriptionRepo) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&CandidateRepo{db: tx}, &SubscriptionRepo{db: tx})
+	})
+}
+
+// Save сохраняет кандидата в организации из ctx. Ожидающий решения кандидат с тем же
+// получателем и валютой обновляется, решённый остаётся как есть. c перечитывается
+// из базы, так что по c.Status видно, был ли он обновлён.
+func (r *CandidateRepo) Save(ctx context.Context, c *model.SubscriptionCandidate) error {
+	tenantID, ok := auth.TenantID(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+	c.TenantID = tenantID
+
+	db := r.db.WithContext(ctx)
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "tenant_id"}, {Name: "user_id"}, {Name: "merchant_key"}, {Name: "currency"}},
+		Where:   clause.Where{Exprs: []clause.Expression{clause.Eq{Column: "subscription_candidates.status", Value: model.CandidatePending}}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"service_name", "price", "billing_unit", "billing_count", "start_date", "end_date",
+			"last_charge_date", "occurrences", "updated_at",
+		}),
+	}).Create(c).Error
+	if err != nil {
+		return err
+	}
+	return db.First(c, "tenant_id = ? AND user_id = ? AND merchant_key = ? AND currency = ?",
+		c.TenantID, c.UserID, c.MerchantKey, c.Currency).Error
+}
+
+// List возвращает кандидатов, видимых вызывающему, в состоянии status (пусто — в любом),
+// новые первыми.
+func (r *CandidateRepo) List(ctx context.Context, userID *uuid.UUID, status string) ([]*model.SubscriptionCandidate, error) {
+	db := r.db.WithContext(ctx).Scopes(candidatesScoped(ctx))
+	if userID != nil {
+		db = db.Where("user_id = ?", *userID)
+	}
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	var candidates []*model.SubscriptionCandidate
+	if err := db.Order("created_at DESC, service_name").Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+func (r *CandidateRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.SubscriptionCandidate, error) {
+	var c model.SubscriptionCandidate
+	if err := r.db.WithContext(ctx).Scopes(candidatesScoped(ctx)).First(&c, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &c, nil
+}
+
+// Decide записывает решение по кандидату, если его ещё не приняли и не отклонили.
+func (r *CandidateRepo) Decide(ctx context.Context, c *model.SubscriptionCandidate) error {
+	res := r.db.WithContext(ctx).Model(c).Scopes(candidatesScoped(ctx)).
+		Where("status = ?", model.CandidatePending).
+		Select("status", "subscription_id", "updated_at").Updates(c)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrAlreadyDecided
+	}
+	return nil
+}
+
+// candidatesScoped ограничивает запрос кандидатами, которые видны вызывающему.
+func candidatesScoped(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = tenantScoped(ctx, "subscription_candidates")(db)
+		if userID := auth.ScopeUserID(ctx); userID != nil {
+			db = db.Where("subscription_candidates.user_id = ?", *userID)
+		}
+		return db
+	}
+}
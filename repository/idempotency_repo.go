@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/model"
+)
+
+type IdempotencyRepo struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepo(db *gorm.DB) *IdempotencyRepo { return &IdempotencyRepo{db: db} }
+
+// Reserve занимает ключ rec в организации ctx. Если ключ уже занят и не истёк,
+// возвращает сохранённую запись; nil означает, что ключ занят этим запросом.
+func (r *IdempotencyRepo) Reserve(ctx context.Context, rec *model.IdempotencyKey) (*model.IdempotencyKey, error) {
+	tenantID, ok := auth.TenantID(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+	rec.TenantID = tenantID
+
+	var existing *model.IdempotencyKey
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// истёкший ключ можно использовать заново, не дожидаясь очистки
+		if err := tx.Scopes(idempotencyKey(rec)).Where("expires_at <= ?", time.Now()).
+			Delete(&model.IdempotencyKey{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(rec)
+		if res.Error != nil || res.RowsAffected > 0 {
+			return res.Error
+		}
+
+		var e model.IdempotencyKey
+		if err := tx.Scopes(idempotencyKey(rec)).First(&e).Error; err != nil {
+			return err
+		}
+		existing = &e
+		return nil
+	})
+	return existing, err
+}
+
+// Complete сохраняет ответ на запрос, занявший ключ.
+func (r *IdempotencyRepo) Complete(ctx context.Context, rec *model.IdempotencyKey) error {
+	return r.db.WithContext(ctx).Model(&model.IdempotencyKey{}).Scopes(idempotencyKey(rec)).
+		Updates(map[string]any{"status": rec.Status, "headers": rec.Headers, "body": rec.Body}).Error
+}
+
+// Release освобождает ключ, чтобы запрос можно было повторить.
+func (r *IdempotencyRepo) Release(ctx context.Context, rec *model.IdempotencyKey) error {
+	return r.db.WithContext(ctx).Scopes(idempotencyKey(rec)).Delete(&model.IdempotencyKey{}).Error
+}
+
+// PurgeExpired удаляет ключи, истёкшие к моменту now, во всех организациях.
+func (r *IdempotencyRepo) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&model.IdempotencyKey{})
+	return res.RowsAffected, res.Error
+}
+
+func idempotencyKey(rec *model.IdempotencyKey) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("tenant_id = ? AND actor = ? AND key = ?", rec.TenantID, rec.Actor, rec.Key)
+	}
+}
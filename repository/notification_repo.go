@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/model"
+)
+
+type NotificationRepo struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepo(db *gorm.DB) *NotificationRepo { return &NotificationRepo{db: db} }
+
+// GetPreference возвращает настройки напоминаний пользователя в организации из ctx.
+func (r *NotificationRepo) GetPreference(ctx context.Context, userID uuid.UUID) (*model.NotificationPreference, error) {
+	var p model.NotificationPreference
+	err := r.db.WithContext(ctx).Scopes(tenantScoped(ctx, "notification_preferences")).
+		First(&p, "user_id = ?", userID).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &p, nil
+}
+
+// SavePreference сохраняет настройки напоминаний пользователя в организации из ctx.
+func (r *NotificationRepo) SavePreference(ctx context.Context, p *model.NotificationPreference) error {
+	tenantID, ok := auth.TenantID(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+	p.TenantID = tenantID
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"email", "renewal_reminders", "ending_reminders", "days_before", "updated_at"}),
+	}).Create(p).Error
+}
+
+// ListSubscribed возвращает настройки всех организаций, в которых включено хоть одно
+// напоминание. Используется планировщиком, работающим вне запроса.
+func (r *NotificationRepo) ListSubscribed(ctx context.Context) ([]*model.NotificationPreference, error) {
+	var prefs []*model.NotificationPreference
+	err := r.db.WithContext(ctx).
+		Where("renewal_reminders OR ending_reminders").
+		Order("tenant_id, user_id").Find(&prefs).Error
+	return prefs, err
+}
+
+// Claim отмечает, что напоминание отправляется. Возвращает false, если его уже
+// отправили или отправляют сейчас: отправлять его не нужно. Отметку без SentAt
+// старше lease оставил упавший процесс, и её можно забрать.
+func (r *NotificationRepo) Claim(ctx context.Context, l *model.ReminderLog, lease time.Duration) (bool, error) {
+	now := time.Now()
+	l.ClaimedAt, l.SentAt = now, nil
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "subscription_id"}, {Name: "kind"}, {Name: "due_date"}},
+		DoUpdates: clause.Assignments(map[string]any{"claimed_at": now}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			gorm.Expr("reminder_logs.sent_at IS NULL AND reminder_logs.claimed_at < ?", now.Add(-lease)),
+		}},
+	}).Create(l)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// MarkSent отмечает напоминание доставленным; повторно его не отправят.
+func (r *NotificationRepo) MarkSent(ctx context.Context, l *model.ReminderLog) error {
+	now := time.Now()
+	l.SentAt = &now
+	return r.db.WithContext(ctx).Model(&model.ReminderLog{}).
+		Where("subscription_id = ? AND kind = ? AND due_date = ?", l.SubscriptionID, l.Kind, l.DueDate).
+		Update("sent_at", now).Error
+}
+
+// Release снимает отметку с напоминания, которое не удалось отправить, чтобы его
+// отправили при следующем запуске.
+func (r *NotificationRepo) Release(ctx context.Context, l *model.ReminderLog) error {
+	return r.db.WithContext(ctx).
+		Where("subscription_id = ? AND kind = ? AND due_date = ? AND sent_at IS NULL", l.SubscriptionID, l.Kind, l.DueDate).
+		Delete(&model.ReminderLog{}).Error
+}
+
+// PurgeReminderLog удаляет отметки о напоминаниях по событиям раньше before:
+// повторно их уже не отправить.
+func (r *NotificationRepo) PurgeReminderLog(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("due_date < ?", before).Delete(&model.ReminderLog{})
+	return res.RowsAffected, res.Error
+}
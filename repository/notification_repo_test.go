@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/model"
+)
+
+func TestSavePreferenceKeepsDisabledReminders(t *testing.T) {
+	subs, ctx := testRepo(t)
+	repo := NewNotificationRepo(subs.db)
+	userID := uuid.New()
+
+	cases := []struct {
+		name            string
+		renewal, ending bool
+	}{
+		{"create disabled", false, false},
+		{"update enabled", true, true},
+		{"update disabled", false, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := repo.SavePreference(ctx, &model.NotificationPreference{
+				UserID:           userID,
+				Email:            "user@example.com",
+				RenewalReminders: tc.renewal,
+				EndingReminders:  tc.ending,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := repo.GetPreference(ctx, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.RenewalReminders != tc.renewal || got.EndingReminders != tc.ending {
+				t.Errorf("reminders = (%v, %v), want (%v, %v)",
+					got.RenewalReminders, got.EndingReminders, tc.renewal, tc.ending)
+			}
+		})
+	}
+}
+
+func TestClaimReminder(t *testing.T) {
+	subs, ctx := testRepo(t)
+	repo := NewNotificationRepo(subs.db)
+	tenantID, _ := auth.TenantID(ctx)
+	subID, userID := uuid.New(), uuid.New()
+	entry := func() *model.ReminderLog {
+		return &model.ReminderLog{
+			SubscriptionID: subID, Kind: model.ReminderRenewal, DueDate: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+			TenantID: tenantID, UserID: userID,
+		}
+	}
+	claim := func(lease time.Duration) bool {
+		t.Helper()
+		ok, err := repo.Claim(ctx, entry(), lease)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	if !claim(time.Hour) {
+		t.Fatal("first claim failed")
+	}
+	if claim(time.Hour) {
+		t.Error("claimed a reminder that is being sent")
+	}
+	// отметку без доставки, оставленную упавшим процессом, забирают после lease
+	if !claim(0) {
+		t.Error("did not reclaim an abandoned reminder")
+	}
+	if err := repo.MarkSent(ctx, entry()); err != nil {
+		t.Fatal(err)
+	}
+	if claim(0) {
+		t.Error("claimed a sent reminder")
+	}
+	if err := repo.Release(ctx, entry()); err != nil {
+		t.Fatal(err)
+	}
+	if claim(0) {
+		t.Error("Release removed a sent reminder")
+	}
+}
+
+// Проверяет без базы, что выключенные напоминания попадают в INSERT, а не
+// заменяются значением по умолчанию столбца.
+func TestSavePreferenceInsertsFalse(t *testing.T) {
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sql string
+	var vars []any
+	err = db.Callback().Create().After("gorm:create").Register("test:capture", func(tx *gorm.DB) {
+		sql, vars = tx.Statement.SQL.String(), tx.Statement.Vars
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := auth.WithTenant(context.Background(), uuid.New())
+	p := &model.NotificationPreference{UserID: uuid.New(), Email: "user@example.com"}
+	if err := NewNotificationRepo(db).SavePreference(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	columns, _, _ := strings.Cut(sql, "VALUES")
+	for _, column := range []string{"renewal_reminders", "ending_reminders"} {
+		if !strings.Contains(columns, column) {
+			t.Errorf("INSERT omits %s: %s", column, sql)
+		}
+	}
+	falses := 0
+	for _, v := range vars {
+		if v == false {
+			falses++
+		}
+	}
+	if falses != 2 {
+		t.Errorf("INSERT vars = %v, want both reminder flags false", vars)
+	}
+}
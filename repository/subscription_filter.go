@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"subscriptions-go/model"
+)
+
+// ErrInvalidCursor возвращается, если курсор страницы повреждён или не подходит к сортировке.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// sortColumns — допустимые поля сортировки и SQL-тип значения в курсоре.
+var sortColumns = map[string]string{
+	"start_date":   "date",
+	"price":        "bigint",
+	"created_at":   "timestamptz",
+	"service_name": "text",
+}
+
+// currentPrice — SQL-выражение цены, действующей в текущем месяце: последнее
+// вступившее в силу изменение из subscription_prices или начальная цена.
+// Совпадает с model.Subscription.CurrentPrice; по нему фильтруют и сортируют по цене.
+const currentPrice = `COALESCE((SELECT sp.price FROM subscription_prices sp
+	WHERE sp.subscription_id = subscriptions.id AND sp.effective_from <= (now() AT TIME ZONE 'UTC')::date
+	ORDER BY sp.effective_from DESC LIMIT 1), subscriptions.price)`
+
+// sortExpr возвращает SQL-выражение поля сортировки.
+func sortExpr(field string) string {
+	if field == "price" {
+		return currentPrice
+	}
+	return field
+}
+
+// ValidSort сообщает, поддерживается ли сортировка по полю.
+func ValidSort(field string) bool {
+	_, ok := sortColumns[field]
+	return ok
+}
+
+// ListFilter — параметры выборки подписок. Все условия применяются в SQL.
+type ListFilter struct {
+	UserID        *uuid.UUID
+	ServiceName   *string // точное совпадение
+	ServicePrefix *string // префикс без учёта регистра
+	MinPrice      *int64
+	MaxPrice      *int64
+	ActiveAt      *time.Time // подписка действует в этом месяце
+	StartFrom     *time.Time
+	StartTo       *time.Time
+	EndFrom       *time.Time
+	EndTo         *time.Time
+	Deleted       bool // только удалённые подписки вместо действующих
+
+	Sort   string // одно из sortColumns, по умолчанию start_date
+	Desc   bool
+	Limit  int
+	Cursor string
+}
+
+// cursor указывает на последнюю строку предыдущей страницы.
+type cursor struct {
+	Sort  string    `json:"s"`
+	Desc  bool      `json:"d,omitempty"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+
+	value any // Value, разобранное по типу поля сортировки
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.value, err = parseCursorValue(c.Sort, c.Value); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// parseCursorValue разбирает значение поля сортировки sort, записанное cursorValue.
+func parseCursorValue(sort, v string) (any, error) {
+	switch sortColumns[sort] {
+	case "date":
+		// дата передаётся строкой: time.Time пришлось бы приводить к date в часовом поясе сессии
+		d, err := time.Parse("2006-01-02", v)
+		return d.Format("2006-01-02"), err
+	case "bigint":
+		return strconv.ParseInt(v, 10, 64)
+	case "timestamptz":
+		return time.Parse(time.RFC3339Nano, v)
+	case "text":
+		return v, nil
+	}
+	return nil, fmt.Errorf("unknown sort field %q", sort)
+}
+
+func (f *ListFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.UserID != nil {
+		db = db.Where("user_id = ?", *f.UserID)
+	}
+	if f.ServiceName != nil {
+		db = db.Where("service_name = ?", *f.ServiceName)
+	}
+	if f.ServicePrefix != nil {
+		db = db.Where("lower(service_name) LIKE ? ESCAPE '\\'", escapeLike(strings.ToLower(*f.ServicePrefix))+"%")
+	}
+	if f.MinPrice != nil {
+		db = db.Where(currentPrice+" >= ?", *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		db = db.Where(currentPrice+" <= ?", *f.MaxPrice)
+	}
+	if f.ActiveAt != nil {
+		db = db.Where("start_date <= ? AND (end_date IS NULL OR end_date >= ?)", *f.ActiveAt, *f.ActiveAt)
+	}
+	if f.StartFrom != nil {
+		db = db.Where("start_date >= ?", *f.StartFrom)
+	}
+	if f.StartTo != nil {
+		db = db.Where("start_date <= ?", *f.StartTo)
+	}
+	if f.EndFrom != nil {
+		db = db.Where("end_date >= ?", *f.EndFrom)
+	}
+	if f.EndTo != nil {
+		db = db.Where("end_date <= ?", *f.EndTo)
+	}
+	return db
+}
+
+// paginate добавляет сортировку по (поле, id) и условие keyset-пагинации.
+func (f *ListFilter) paginate(db *gorm.DB) (*gorm.DB, error) {
+	col := sortExpr(f.Sort)
+	dir, cmp := "ASC", ">"
+	if f.Desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if f.Cursor != "" {
+		c, err := decodeCursor(f.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if c.Sort != f.Sort || c.Desc != f.Desc {
+			return nil, fmt.Errorf("%w: cursor was issued for a different sort order", ErrInvalidCursor)
+		}
+		db = db.Where(fmt.Sprintf("(%s, id) %s (?::%s, ?)", col, cmp, sortColumns[f.Sort]), c.value, c.ID)
+	}
+
+	return db.Order(fmt.Sprintf("%s %s, id %s", col, dir, dir)).Limit(f.Limit + 1), nil
+}
+
+func (f *ListFilter) normalize() error {
+	if f.Sort == "" {
+		f.Sort = "start_date"
+	}
+	if !ValidSort(f.Sort) {
+		return fmt.Errorf("unknown sort field %q", f.Sort)
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit > MaxPageSize {
+		f.Limit = MaxPageSize
+	}
+	return nil
+}
+
+func cursorValue(sub *model.Subscription, sort string) string {
+	switch sort {
+	case "price":
+		return strconv.FormatInt(sub.CurrentPrice, 10)
+	case "created_at":
+		return sub.CreatedAt.Format(time.RFC3339Nano)
+	case "service_name":
+		return sub.ServiceName
+	default:
+		return sub.StartDate.Format("2006-01-02")
+	}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"subscriptions-go/model"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	id := uuid.New()
+	sub := &model.Subscription{
+		ID:           id,
+		ServiceName:  "Netflix",
+		Price:        999,
+		CurrentPrice: 1549,
+		StartDate:    time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		CreatedAt:    time.Date(2024, 3, 2, 10, 30, 15, 123456000, time.UTC),
+	}
+	tests := []struct {
+		sort string
+		want any
+	}{
+		{"start_date", "2024-03-01"},
+		{"price", int64(1549)},
+		{"created_at", sub.CreatedAt},
+		{"service_name", "Netflix"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			c, err := decodeCursor(encodeCursor(cursor{Sort: tt.sort, Value: cursorValue(sub, tt.sort), ID: id}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, ok := c.value.(time.Time); ok {
+				if !got.Equal(tt.want.(time.Time)) {
+					t.Errorf("value = %v, want %v", got, tt.want)
+				}
+			} else if c.value != tt.want {
+				t.Errorf("value = %#v, want %#v", c.value, tt.want)
+			}
+			if c.ID != id {
+				t.Errorf("id = %s, want %s", c.ID, id)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	raw := func(json string) string { return base64.RawURLEncoding.EncodeToString([]byte(json)) }
+	id := uuid.NewString()
+	tests := []struct {
+		name, cursor string
+	}{
+		{"not base64", "!!!"},
+		{"not JSON", raw("start_date")},
+		{"bad id", raw(`{"s":"price","v":"1","id":"x"}`)},
+		{"unknown sort", raw(`{"s":"user_id","v":"1","id":"` + id + `"}`)},
+		{"date", raw(`{"s":"start_date","v":"2024-13-01","id":"` + id + `"}`)},
+		{"date with SQL", raw(`{"s":"start_date","v":"2024-01-01'::date OR true --","id":"` + id + `"}`)},
+		{"price", raw(`{"s":"price","v":"12.5","id":"` + id + `"}`)},
+		{"created_at", raw(`{"s":"created_at","v":"yesterday","id":"` + id + `"}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decodeCursor() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestSearchByCurrentPrice(t *testing.T) {
+	repo, ctx := testRepo(t)
+
+	now := time.Now().UTC()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	sub := &model.Subscription{
+		ServiceName: "Netflix", Price: 100, Currency: "RUB", UserID: uuid.New(),
+		StartDate: thisMonth.AddDate(-1, 0, 0),
+	}
+	if err := repo.Create(ctx, sub); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SavePrice(ctx, &model.SubscriptionPrice{SubscriptionID: sub.ID, Price: 500, EffectiveFrom: thisMonth}); err != nil {
+		t.Fatal(err)
+	}
+	// будущее изменение ещё не действует
+	if err := repo.SavePrice(ctx, &model.SubscriptionPrice{SubscriptionID: sub.ID, Price: 900, EffectiveFrom: thisMonth.AddDate(0, 1, 0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	price := func(n int64) *int64 { return &n }
+	tests := []struct {
+		name     string
+		min, max *int64
+		want     int
+	}{
+		{"current price in range", price(400), price(600), 1},
+		{"initial price only", nil, price(200), 0},
+		{"future price only", price(800), nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.Search(ctx, ListFilter{UserID: &sub.UserID, MinPrice: tt.min, MaxPrice: tt.max, Sort: "price"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Items) != tt.want {
+				t.Fatalf("got %d subscriptions, want %d", len(page.Items), tt.want)
+			}
+			if tt.want > 0 {
+				if got := page.Items[0]; got.Price != 100 || got.CurrentPrice != 500 {
+					t.Errorf("price = %d, current_price = %d, want 100 and 500", got.Price, got.CurrentPrice)
+				}
+			}
+		})
+	}
+}
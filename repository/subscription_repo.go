@@ -1,33 +1,70 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"subscriptions-go/auth"
 	"subscriptions-go/model"
 )
 
+// ErrNotFound возвращается, если запись не найдена.
+var ErrNotFound = errors.New("record not found")
+
+// ErrNoTenant возвращается, если в контексте запроса не определена организация.
+// Без организации репозиторий не выполняет запросов к подпискам.
+var ErrNoTenant = errors.New("tenant is not set")
+
+// ErrVersionMismatch возвращается, если подписка изменилась после того, как вызывающий её прочитал.
+var ErrVersionMismatch = errors.New("subscription version mismatch")
+
+// ErrOverlap возвращается, когда запись нарушает ограничение subscriptions_no_overlap.
+var ErrOverlap = errors.New("subscription period overlaps")
+
 type SubscriptionRepo struct {
 	db *gorm.DB
 }
 
 func NewSubscriptionRepo(db *gorm.DB) *SubscriptionRepo { return &SubscriptionRepo{db: db} }
 
-func (r *SubscriptionRepo) Create(sub *model.Subscription) error {
-	return r.db.Create(sub).Error
+// Transaction выполняет fn в транзакции; репозиторий, переданный в fn, работает внутри неё.
+func (r *SubscriptionRepo) Transaction(ctx context.Context, fn func(tx *SubscriptionRepo) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&SubscriptionRepo{db: tx})
+	})
 }
 
-func (r *SubscriptionRepo) GetByID(id uuid.UUID) (*model.Subscription, error) {
+// Create создаёт подписку в организации из ctx.
+func (r *SubscriptionRepo) Create(ctx context.Context, sub *model.Subscription) error {
+	tenantID, ok := auth.TenantID(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+	sub.TenantID = tenantID
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(sub).Error; err != nil {
+			return translateError(err)
+		}
+		return writeAudit(ctx, tx, sub.TenantID, sub.ID, model.AuditCreate, nil, sub)
+	})
+}
+
+func (r *SubscriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Subscription, error) {
 	var s model.Subscription
-	if err := r.db.First(&s, "id = ?", id).Error; err != nil {
-		return nil, err
+	if err := r.db.WithContext(ctx).Scopes(scoped(ctx), withHistory).First(&s, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
 	}
 	return &s, nil
 }
 
-func (r *SubscriptionRepo) List(userID *uuid.UUID, serviceName *string) ([]*model.Subscription, error) {
-	db := r.db.Model(&model.Subscription{})
+func (r *SubscriptionRepo) List(ctx context.Context, userID *uuid.UUID, serviceName *string) ([]*model.Subscription, error) {
+	db := r.db.WithContext(ctx).Model(&model.Subscription{}).Scopes(scoped(ctx), withHistory)
 
 	if userID != nil {
 		db = db.Where("user_id = ?", *userID)
@@ -44,35 +81,326 @@ func (r *SubscriptionRepo) List(userID *uuid.UUID, serviceName *string) ([]*mode
 	return subs, nil
 }
 
-func (r *SubscriptionRepo) Update(sub *model.Subscription) error {
-	return r.db.Save(sub).Error
+// Owner — пользователь и сервис: в их пределах периоды подписок не пересекаются.
+type Owner struct {
+	UserID      uuid.UUID
+	ServiceName string
+}
+
+// ListByOwners одним запросом возвращает подписки указанных пар пользователь — сервис.
+func (r *SubscriptionRepo) ListByOwners(ctx context.Context, owners []Owner) ([]*model.Subscription, error) {
+	if len(owners) == 0 {
+		return nil, nil
+	}
+	pairs := make([][]any, 0, len(owners))
+	for _, o := range owners {
+		pairs = append(pairs, []any{o.UserID, o.ServiceName})
+	}
+
+	var subs []*model.Subscription
+	err := r.db.WithContext(ctx).Scopes(scoped(ctx), withHistory).
+		Where("(subscriptions.user_id, subscriptions.service_name) IN ?", pairs).
+		Find(&subs).Error
+	return subs, err
 }
 
-func (r *SubscriptionRepo) Delete(id uuid.UUID) error {
-	return r.db.Delete(&model.Subscription{}, "id = ?", id).Error
+// ListByIDs возвращает видимые подписки с указанными идентификаторами; ненайденные пропускаются.
+func (r *SubscriptionRepo) ListByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Subscription, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var subs []*model.Subscription
+	err := r.db.WithContext(ctx).Scopes(scoped(ctx), withHistory).
+		Where("subscriptions.id IN ?", ids).
+		Find(&subs).Error
+	return subs, err
 }
 
-// Sum of price where subscription period intersects [periodStart, periodEnd].
-// periodStart and periodEnd are dates representing first day of months.
-func (r *SubscriptionRepo) SumPriceForPeriod(periodStart, periodEnd time.Time, userID *uuid.UUID, serviceName *string) (int64, error) {
-	// SQL: sum price where (end_date is null and start_date <= periodEnd) OR (end_date is not null and start_date <= periodEnd and end_date >= periodStart)
-	q := r.db.Model(&model.Subscription{}).Select("COALESCE(SUM(price),0) as total")
+// Page — страница результатов; NextCursor пуст на последней странице.
+type Page struct {
+	Items      []*model.Subscription `json:"items"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// Search возвращает страницу подписок по фильтру с keyset-пагинацией.
+func (r *SubscriptionRepo) Search(ctx context.Context, f ListFilter) (*Page, error) {
+	if err := f.normalize(); err != nil {
+		return nil, err
+	}
+
+	db := r.db.WithContext(ctx).Model(&model.Subscription{})
+	if f.Deleted {
+		db = db.Unscoped().Where("subscriptions.deleted_at IS NOT NULL")
+	}
+	db, err := f.paginate(f.apply(db.Scopes(scoped(ctx), withHistory)))
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []*model.Subscription
+	if err := db.Find(&subs).Error; err != nil {
+		return nil, err
+	}
+
+	page := &Page{Items: subs}
+	if len(subs) > f.Limit {
+		page.Items = subs[:f.Limit]
+		last := page.Items[f.Limit-1]
+		page.NextCursor = encodeCursor(cursor{Sort: f.Sort, Desc: f.Desc, Value: cursorValue(last, f.Sort), ID: last.ID})
+	}
+	return page, nil
+}
 
-	q = q.Where(
-		r.db.Where("end_date IS NULL AND start_date <= ?", periodEnd).
-			Or("end_date IS NOT NULL AND start_date <= ? AND end_date >= ?", periodEnd, periodStart),
-	)
+// ListTrialsEnding возвращает подписки, пробный период которых заканчивается в [from, to].
+func (r *SubscriptionRepo) ListTrialsEnding(ctx context.Context, from, to time.Time, userID *uuid.UUID) ([]*model.Subscription, error) {
+	db := r.db.WithContext(ctx).Model(&model.Subscription{}).Scopes(scoped(ctx), withHistory).
+		Where("trial_end_date BETWEEN ? AND ?", from, to).
+		Where("end_date IS NULL OR end_date >= trial_end_date")
 
 	if userID != nil {
-		q = q.Where("user_id = ?", *userID)
+		db = db.Where("user_id = ?", *userID)
 	}
-	if serviceName != nil {
-		q = q.Where("service_name = ?", *serviceName)
+
+	var subs []*model.Subscription
+	if err := db.Order("trial_end_date").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+// Update перезаписывает все поля подписки, кроме организации, если её версия
+// всё ещё равна sub.Version (иначе ErrVersionMismatch), и увеличивает версию.
+// Подписка, недоступная вызывающему, не изменяется (ErrNotFound).
+func (r *SubscriptionRepo) Update(ctx context.Context, sub *model.Subscription) error {
+	expected := sub.Version
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		before, err := lockSubscription(ctx, tx, sub.ID, false)
+		if err != nil {
+			return err
+		}
+		if before.Version != expected {
+			return ErrVersionMismatch
+		}
+
+		sub.Version = expected + 1
+		res := tx.Model(sub).Scopes(scoped(ctx)).Where("version = ?", expected).
+			Select("*").Omit(clause.Associations, "id", "tenant_id", "created_at", "deleted_at").
+			Updates(sub)
+		if res.Error != nil {
+			return translateError(res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return ErrVersionMismatch
+		}
+		return writeAudit(ctx, tx, before.TenantID, sub.ID, model.AuditUpdate, before, sub)
+	})
+	if err != nil {
+		sub.Version = expected
 	}
+	return err
+}
+
+// SavePrice сохраняет изменение цены; изменение с той же датой начала действия заменяется.
+func (r *SubscriptionRepo) SavePrice(ctx context.Context, p *model.SubscriptionPrice) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		sub, err := lockSubscription(ctx, tx, p.SubscriptionID, false)
+		if err != nil {
+			return err
+		}
+
+		var before *model.SubscriptionPrice
+		var existing model.SubscriptionPrice
+		err = tx.Where("subscription_id = ? AND effective_from = ?", p.SubscriptionID, p.EffectiveFrom).Take(&existing).Error
+		switch {
+		case err == nil:
+			before = &existing
+			p.ID, p.CreatedAt = existing.ID, existing.CreatedAt
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "subscription_id"}, {Name: "effective_from"}},
+			DoUpdates: clause.AssignmentColumns([]string{"price"}),
+		}).Create(p).Error
+		if err != nil {
+			return err
+		}
+		if err := bumpVersion(tx, sub.ID); err != nil {
+			return err
+		}
+		return writeAudit(ctx, tx, sub.TenantID, sub.ID, model.AuditPriceChange, before, p)
+	})
+}
+
+// SavePause создаёт паузу или обновляет существующую (например, при возобновлении).
+func (r *SubscriptionRepo) SavePause(ctx context.Context, p *model.SubscriptionPause) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		sub, err := lockSubscription(ctx, tx, p.SubscriptionID, false)
+		if err != nil {
+			return err
+		}
+
+		action := model.AuditPause
+		var before *model.SubscriptionPause
+		if p.ID != uuid.Nil {
+			var existing model.SubscriptionPause
+			if err := tx.Where("id = ? AND subscription_id = ?", p.ID, p.SubscriptionID).Take(&existing).Error; err != nil {
+				return translateError(err)
+			}
+			before, action = &existing, model.AuditResume
+		}
+
+		if err := tx.Save(p).Error; err != nil {
+			return err
+		}
+		if err := bumpVersion(tx, sub.ID); err != nil {
+			return err
+		}
+		return writeAudit(ctx, tx, sub.TenantID, sub.ID, action, before, p)
+	})
+}
+
+// DeletePause удаляет паузу, так и не вступившую в силу.
+func (r *SubscriptionRepo) DeletePause(ctx context.Context, p *model.SubscriptionPause) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		sub, err := lockSubscription(ctx, tx, p.SubscriptionID, false)
+		if err != nil {
+			return err
+		}
+
+		var before model.SubscriptionPause
+		if err := tx.Where("id = ? AND subscription_id = ?", p.ID, p.SubscriptionID).Take(&before).Error; err != nil {
+			return translateError(err)
+		}
+		if err := tx.Delete(&before).Error; err != nil {
+			return err
+		}
+		if err := bumpVersion(tx, sub.ID); err != nil {
+			return err
+		}
+		return writeAudit(ctx, tx, sub.TenantID, sub.ID, model.AuditResume, &before, nil)
+	})
+}
+
+// bumpVersion увеличивает версию подписки при изменении её истории цен и пауз.
+func bumpVersion(tx *gorm.DB, id uuid.UUID) error {
+	return tx.Unscoped().Model(&model.Subscription{}).Where("id = ?", id).
+		UpdateColumn("version", gorm.Expr("version + 1")).Error
+}
+
+// lockSubscription загружает подписку, доступную вызывающему, и блокирует её строку
+// до конца транзакции tx. deleted выбирает удалённые подписки вместо действующих.
+func lockSubscription(ctx context.Context, tx *gorm.DB, id uuid.UUID, deleted bool) (*model.Subscription, error) {
+	db := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	if deleted {
+		db = db.Unscoped().Where("subscriptions.deleted_at IS NOT NULL")
+	}
+
+	var s model.Subscription
+	if err := db.Scopes(scoped(ctx)).First(&s, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &s, nil
+}
+
+// translateError переводит ошибки базы в ошибки репозитория:
+// отсутствие записи — в ErrNotFound, нарушение ограничения пересечения периодов — в ErrOverlap.
+func translateError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23P01" && pgErr.ConstraintName == "subscriptions_no_overlap" {
+		return ErrOverlap
+	}
+	return err
+}
+
+// scoped ограничивает запрос подписками организации из ctx, доступными вызывающему.
+// Без организации запрос завершается ошибкой ErrNoTenant.
+func scoped(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = tenantScoped(ctx, "subscriptions")(db)
+		if userID := auth.ScopeUserID(ctx); userID != nil {
+			db = db.Where("subscriptions.user_id = ?", *userID)
+		}
+		return db
+	}
+}
+
+// withHistory подгружает историю цен и пауз подписки.
+func withHistory(db *gorm.DB) *gorm.DB {
+	return db.Preload("Prices", orderPrices).Preload("Pauses", orderPauses)
+}
+
+func orderPrices(db *gorm.DB) *gorm.DB {
+	return db.Order("effective_from")
+}
+
+func orderPauses(db *gorm.DB) *gorm.DB {
+	return db.Order("start_date")
+}
+
+// Delete помечает подписку удалённой, если её версия всё ещё равна version
+// (иначе ErrVersionMismatch); удалённую подписку можно восстановить до очистки.
+func (r *SubscriptionRepo) Delete(ctx context.Context, id uuid.UUID, version int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		before, err := lockSubscription(ctx, tx, id, false)
+		if err != nil {
+			return err
+		}
+		if before.Version != version {
+			return ErrVersionMismatch
+		}
+
+		res := tx.Where("version = ?", version).Delete(&model.Subscription{}, "id = ?", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrVersionMismatch
+		}
+		return writeAudit(ctx, tx, before.TenantID, id, model.AuditDelete, before, nil)
+	})
+}
 
-	var total int64
-	if err := q.Scan(&total).Error; err != nil {
-		return 0, err
+// GetDeleted возвращает удалённую подписку, доступную вызывающему.
+func (r *SubscriptionRepo) GetDeleted(ctx context.Context, id uuid.UUID) (*model.Subscription, error) {
+	var s model.Subscription
+	err := r.db.WithContext(ctx).Unscoped().Scopes(scoped(ctx), withHistory).
+		Where("subscriptions.deleted_at IS NOT NULL").
+		First(&s, "id = ?", id).Error
+	if err != nil {
+		return nil, translateError(err)
 	}
-	return total, nil
+	return &s, nil
+}
+
+// Restore снимает пометку об удалении.
+func (r *SubscriptionRepo) Restore(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		before, err := lockSubscription(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Unscoped().Model(&model.Subscription{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}).Error
+		if err != nil {
+			return translateError(err)
+		}
+		after := *before
+		after.DeletedAt = gorm.DeletedAt{}
+		after.Version++
+		return writeAudit(ctx, tx, before.TenantID, id, model.AuditRestore, before, &after)
+	})
+}
+
+// PurgeDeleted окончательно удаляет подписки, удалённые раньше before, во всех организациях.
+// Вызывается фоновой очисткой, а не в рамках запроса. История цен и пауз удаляется каскадно.
+func (r *SubscriptionRepo) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Unscoped().Where("deleted_at < ?", before).Delete(&model.Subscription{})
+	return res.RowsAffected, res.Error
 }
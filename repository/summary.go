@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"subscriptions-go/auth"
+)
+
+// ChargeFilter — параметры агрегации списаний за период.
+type ChargeFilter struct {
+	PeriodStart time.Time // первое число первого месяца
+	PeriodEnd   time.Time // первое число месяца, следующего за последним (не включительно)
+	UserID      *uuid.UUID
+	ServiceName *string
+
+	GroupByService bool
+	GroupByUser    bool
+	GroupByMonth   bool
+}
+
+// ChargeTotal — сумма списаний в одной валюте. Поля группировки, не запрошенные
+// в ChargeFilter, пустые; Month имеет формат YYYY-MM.
+type ChargeTotal struct {
+	ServiceName string
+	UserID      string
+	Month       string
+	Currency    string
+	Amount      int64
+}
+
+// chargesSQL перечисляет списания каждой подписки внутри периода.
+//
+// Даты списаний — StartDate + k * шаг. Ряд generate_series начинается с первого
+// списания не раньше начала периода, поэтому давние подписки не порождают лишних строк.
+// Подписка оплачивается по месяц end_date включительно. Цена списания — последнее
+// изменение из subscription_prices, вступившее в силу к дате списания, иначе базовая цена.
+// Списания в пробный период и на паузе, а также удалённые подписки не учитываются.
+const chargesSQL = `
+WITH subs AS (
+	SELECT s.id, s.service_name, s.user_id, s.currency, s.price, s.trial_end_date, s.start_date,
+		CASE s.billing_unit
+			WHEN 'week' THEN make_interval(days => 7 * s.billing_count)
+			WHEN 'year' THEN make_interval(years => s.billing_count)
+			ELSE make_interval(months => s.billing_count)
+		END AS step,
+		CASE s.billing_unit
+			WHEN 'week' THEN s.start_date + (7 * s.billing_count) *
+				((GREATEST(CAST(@period_start AS date) - s.start_date, 0) + 7 * s.billing_count - 1) / (7 * s.billing_count))
+			ELSE (s.start_date + make_interval(months => s.step_months *
+				((GREATEST(((EXTRACT(YEAR FROM CAST(@period_start AS date)) - EXTRACT(YEAR FROM s.start_date)) * 12
+					+ EXTRACT(MONTH FROM CAST(@period_start AS date)) - EXTRACT(MONTH FROM s.start_date))::int, 0)
+					+ s.step_months - 1) / s.step_months)))::date
+		END AS first_charge,
+		LEAST(CAST(@period_end AS date), COALESCE((s.end_date + interval '1 month')::date, CAST(@period_end AS date))) AS until
+	FROM (
+		SELECT *, CASE billing_unit WHEN 'year' THEN 12 * billing_count ELSE billing_count END AS step_months
+		FROM subscriptions
+	) s
+	WHERE s.deleted_at IS NULL AND s.start_date < @period_end AND (s.end_date IS NULL OR s.end_date >= @period_start) {{filters}}
+),
+charges AS (
+	SELECT subs.id, subs.service_name, subs.user_id, subs.currency, subs.price, subs.trial_end_date,
+		c.at::date AS charge_date
+	FROM subs
+	CROSS JOIN LATERAL generate_series(subs.first_charge::timestamp, subs.until::timestamp - interval '1 day', subs.step) AS c(at)
+)
+SELECT {{columns}}, ch.currency, SUM(COALESCE(p.price, ch.price))::bigint AS amount
+FROM charges ch
+LEFT JOIN LATERAL (
+	SELECT sp.price FROM subscription_prices sp
+	WHERE sp.subscription_id = ch.id AND sp.effective_from <= ch.charge_date
+	ORDER BY sp.effective_from DESC
+	LIMIT 1
+) p ON true
+WHERE (ch.trial_end_date IS NULL OR ch.charge_date >= ch.trial_end_date)
+	AND NOT EXISTS (
+		SELECT 1 FROM subscription_pauses pa
+		WHERE pa.subscription_id = ch.id
+			AND ch.charge_date >= pa.start_date
+			AND (pa.end_date IS NULL OR ch.charge_date < pa.end_date)
+	)
+GROUP BY {{groups}}
+`
+
+// SumCharges считает суммы списаний за период в PostgreSQL, сгруппированные по валюте
+// и запрошенным полям.
+func (r *SubscriptionRepo) SumCharges(ctx context.Context, f ChargeFilter) ([]ChargeTotal, error) {
+	// даты передаются строками, чтобы приведение к date не зависело от часового пояса сессии
+	args := map[string]interface{}{
+		"period_start": f.PeriodStart.Format("2006-01-02"),
+		"period_end":   f.PeriodEnd.Format("2006-01-02"),
+	}
+
+	tenantID, ok := auth.TenantID(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+	args["tenant_id"] = tenantID
+
+	filters := []string{"AND s.tenant_id = @tenant_id"}
+	if userID := auth.ScopeUserID(ctx); userID != nil {
+		filters = append(filters, "AND s.user_id = @scope_user_id")
+		args["scope_user_id"] = *userID
+	}
+	if f.UserID != nil {
+		filters = append(filters, "AND s.user_id = @user_id")
+		args["user_id"] = *f.UserID
+	}
+	if f.ServiceName != nil {
+		filters = append(filters, "AND s.service_name = @service_name")
+		args["service_name"] = *f.ServiceName
+	}
+
+	columns := []string{"''", "''", "''"}
+	groups := []string{"ch.currency"}
+	if f.GroupByService {
+		columns[0] = "ch.service_name"
+		groups = append(groups, "ch.service_name")
+	}
+	if f.GroupByUser {
+		columns[1] = "ch.user_id::text"
+		groups = append(groups, "ch.user_id")
+	}
+	if f.GroupByMonth {
+		columns[2] = "to_char(ch.charge_date, 'YYYY-MM')"
+		groups = append(groups, "to_char(ch.charge_date, 'YYYY-MM')")
+	}
+	columns[0] += " AS service_name"
+	columns[1] += " AS user_id"
+	columns[2] += " AS month"
+
+	query := strings.NewReplacer(
+		"{{filters}}", strings.Join(filters, " "),
+		"{{columns}}", strings.Join(columns, ", "),
+		"{{groups}}", strings.Join(groups, ", "),
+	).Replace(chargesSQL)
+
+	var rows []ChargeTotal
+	if err := r.db.WithContext(ctx).Raw(query, args).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
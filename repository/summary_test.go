@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/db"
+	"subscriptions-go/model"
+)
+
+// testRepo открывает транзакцию в тестовой базе TEST_DATABASE_URL и откатывает её
+// по завершении теста. Возвращает также контекст с новой организацией, в которой
+// работает тест. Без TEST_DATABASE_URL тест пропускается.
+func testRepo(t *testing.T) (*SubscriptionRepo, context.Context) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	conn, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Migrate(context.Background(), conn); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := conn.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+
+	tenant := &model.Tenant{Name: "test-" + uuid.NewString()}
+	if err := NewTenantRepo(tx).Create(context.Background(), tenant); err != nil {
+		t.Fatal(err)
+	}
+	return NewSubscriptionRepo(tx), auth.WithTenant(context.Background(), tenant.ID)
+}
+
+func month(mmYYYY string) time.Time {
+	t, err := time.Parse("01-2006", mmYYYY)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func monthPtr(mmYYYY string) *time.Time {
+	t := month(mmYYYY)
+	return &t
+}
+
+func TestSumCharges(t *testing.T) {
+	tests := []struct {
+		name   string
+		sub    model.Subscription
+		prices []model.SubscriptionPrice
+		pauses []model.SubscriptionPause
+		delete bool   // удалить подписку перед расчётом
+		start  string // первый месяц периода
+		end    string // последний месяц периода, включительно
+		want   int64
+	}{
+		{
+			name:  "open-ended started before period",
+			sub:   model.Subscription{StartDate: month("06-2024")},
+			start: "01-2025", end: "03-2025",
+			want: 300,
+		},
+		{
+			name:  "single-month period",
+			sub:   model.Subscription{StartDate: month("01-2024")},
+			start: "02-2025", end: "02-2025",
+			want: 100,
+		},
+		{
+			name:  "same-month start and end",
+			sub:   model.Subscription{StartDate: month("03-2025"), EndDate: monthPtr("03-2025")},
+			start: "01-2025", end: "06-2025",
+			want: 100,
+		},
+		{
+			name:  "period fully inside subscription",
+			sub:   model.Subscription{StartDate: month("01-2024"), EndDate: monthPtr("12-2026")},
+			start: "03-2025", end: "05-2025",
+			want: 300,
+		},
+		{
+			name:  "subscription fully inside period",
+			sub:   model.Subscription{StartDate: month("02-2025"), EndDate: monthPtr("03-2025")},
+			start: "01-2025", end: "12-2025",
+			want: 200,
+		},
+		{
+			name:  "end month is first month of period",
+			sub:   model.Subscription{StartDate: month("01-2024"), EndDate: monthPtr("01-2025")},
+			start: "01-2025", end: "03-2025",
+			want: 100,
+		},
+		{
+			name:  "ended before period",
+			sub:   model.Subscription{StartDate: month("01-2024"), EndDate: monthPtr("12-2024")},
+			start: "01-2025", end: "03-2025",
+			want: 0,
+		},
+		{
+			name:  "starts after period",
+			sub:   model.Subscription{StartDate: month("04-2025")},
+			start: "01-2025", end: "03-2025",
+			want: 0,
+		},
+		{
+			name:  "quarterly",
+			sub:   model.Subscription{StartDate: month("11-2024"), BillingUnit: model.BillingMonth, BillingCount: 3},
+			start: "02-2025", end: "09-2025",
+			want: 300,
+		},
+		{
+			name:  "yearly",
+			sub:   model.Subscription{StartDate: month("03-2024"), BillingUnit: model.BillingYear, BillingCount: 1},
+			start: "01-2025", end: "12-2025",
+			want: 100,
+		},
+		{
+			name:  "weekly",
+			sub:   model.Subscription{StartDate: month("01-2025"), BillingUnit: model.BillingWeek, BillingCount: 1},
+			start: "02-2025", end: "02-2025",
+			want: 400,
+		},
+		{
+			name:   "price change applies from its month",
+			sub:    model.Subscription{StartDate: month("01-2025")},
+			prices: []model.SubscriptionPrice{{Price: 200, EffectiveFrom: month("03-2025")}},
+			start:  "01-2025", end: "04-2025",
+			want: 600,
+		},
+		{
+			name:  "trial months are free",
+			sub:   model.Subscription{StartDate: month("01-2025"), TrialEndDate: monthPtr("03-2025")},
+			start: "01-2025", end: "04-2025",
+			want: 200,
+		},
+		{
+			name:   "paused months are excluded",
+			sub:    model.Subscription{StartDate: month("01-2025")},
+			pauses: []model.SubscriptionPause{{StartDate: month("02-2025"), EndDate: monthPtr("04-2025")}},
+			start:  "01-2025", end: "05-2025",
+			want: 300,
+		},
+		{
+			name:   "deleted subscription is excluded",
+			sub:    model.Subscription{StartDate: month("01-2025")},
+			delete: true,
+			start:  "01-2025", end: "03-2025",
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, ctx := testRepo(t)
+
+			sub := tt.sub
+			sub.ServiceName = "Test"
+			sub.Price = 100
+			sub.Currency = "RUB"
+			sub.UserID = uuid.New()
+			if sub.BillingUnit == "" {
+				sub.BillingUnit, sub.BillingCount = model.BillingMonth, 1
+			}
+			if err := repo.Create(ctx, &sub); err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range tt.prices {
+				p.SubscriptionID = sub.ID
+				if err := repo.SavePrice(ctx, &p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, p := range tt.pauses {
+				p.SubscriptionID = sub.ID
+				if err := repo.SavePause(ctx, &p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.delete {
+				if err := repo.Delete(ctx, sub.ID, sub.Version); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			rows, err := repo.SumCharges(ctx, ChargeFilter{
+				PeriodStart: month(tt.start),
+				PeriodEnd:   month(tt.end).AddDate(0, 1, 0),
+				UserID:      &sub.UserID,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got int64
+			for _, row := range rows {
+				got += row.Amount
+			}
+			if got != tt.want {
+				t.Errorf("SumCharges() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumChargesGroupByMonth(t *testing.T) {
+	repo, ctx := testRepo(t)
+
+	userID := uuid.New()
+	for _, name := range []string{"A", "B"} {
+		sub := model.Subscription{
+			ServiceName: name, Price: 100, Currency: "RUB", UserID: userID,
+			StartDate: month("01-2025"), BillingUnit: model.BillingMonth, BillingCount: 1,
+		}
+		if err := repo.Create(ctx, &sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, err := repo.SumCharges(ctx, ChargeFilter{
+		PeriodStart:  month("01-2025"),
+		PeriodEnd:    month("03-2025"),
+		UserID:       &userID,
+		GroupByMonth: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]int64)
+	for _, row := range rows {
+		got[row.Month] += row.Amount
+	}
+	want := map[string]int64{"2025-01": 200, "2025-02": 200}
+	if len(got) != len(want) || got["2025-01"] != want["2025-01"] || got["2025-02"] != want["2025-02"] {
+		t.Errorf("SumCharges() by month = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/model"
+)
+
+type TenantRepo struct {
+	db *gorm.DB
+}
+
+func NewTenantRepo(db *gorm.DB) *TenantRepo { return &TenantRepo{db: db} }
+
+// ErrTenantExists возвращается, если организация с таким именем уже есть.
+var ErrTenantExists = errors.New("tenant already exists")
+
+func (r *TenantRepo) Create(ctx context.Context, t *model.Tenant) error {
+	err := r.db.WithContext(ctx).Create(t).Error
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrTenantExists
+	}
+	return err
+}
+
+// List возвращает все организации по имени.
+func (r *TenantRepo) List(ctx context.Context) ([]*model.Tenant, error) {
+	var tenants []*model.Tenant
+	err := r.db.WithContext(ctx).Order("name").Find(&tenants).Error
+	return tenants, err
+}
+
+// Exists сообщает, зарегистрирована ли организация.
+func (r *TenantRepo) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	var n int64
+	if err := r.db.WithContext(ctx).Model(&model.Tenant{}).Where("id = ?", id).Count(&n).Error; err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// tenantScoped ограничивает запрос строками таблицы table из организации ctx.
+// Без организации запрос завершается ошибкой ErrNoTenant.
+func tenantScoped(ctx context.Context, table string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		tenantID, ok := auth.TenantID(ctx)
+		if !ok {
+			_ = db.AddError(ErrNoTenant)
+			return db
+		}
+		return db.Where(table+".tenant_id = ?", tenantID)
+	}
+}
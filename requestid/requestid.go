@@ -0,0 +1,21 @@
+// Package requestid передаёт идентификатор HTTP-запроса через context.
+package requestid
+
+import "context"
+
+// Header — заголовок, в котором клиент может передать идентификатор запроса
+// и в котором сервис его возвращает.
+const Header = "X-Request-ID"
+
+type key struct{}
+
+// With кладёт идентификатор запроса в контекст.
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// From возвращает идентификатор запроса или пустую строку.
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/model"
+	"subscriptions-go/repository"
+)
+
+// apiKeyPrefix отличает API-ключи от других секретов (например, при поиске утечек).
+// Формат ключа: sk_<ID ключа в hex>_<секрет в base64url>.
+const apiKeyPrefix = "sk_"
+
+// ErrAPIKeyNotFound возвращается, если API-ключ не найден в организации вызывающего.
+var ErrAPIKeyNotFound error = &notFoundError{what: "api key"}
+
+// IssuedAPIKey — ключ вместе с секретом. Секрет не хранится и возвращается
+// только при выпуске и ротации ключа.
+type IssuedAPIKey struct {
+	*model.APIKey
+	Key string `json:"key"`
+}
+
+type APIKeyService struct {
+	repo *repository.APIKeyRepo
+}
+
+func NewAPIKeyService(r *repository.APIKeyRepo) *APIKeyService {
+	return &APIKeyService{repo: r}
+}
+
+// Create выпускает ключ с разрешениями scopes, действующий от имени вызывающего.
+// Выдать можно только разрешения, которые есть у самого вызывающего.
+func (s *APIKeyService) Create(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (*IssuedAPIKey, error) {
+	verr := &ValidationError{}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		verr.Add("name", "is required")
+	}
+	if len(scopes) == 0 {
+		verr.Add("scopes", "must not be empty")
+	}
+	for _, scope := range scopes {
+		if !auth.ValidPermission(auth.Permission(scope)) {
+			verr.Add("scopes", fmt.Sprintf("unknown scope %q", scope))
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		verr.Add("expires_at", "must be in the future")
+	}
+	if err := verr.OrNil(); err != nil {
+		return nil, err
+	}
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, forbidden("api keys can only be issued by an authenticated user")
+	}
+	for _, scope := range scopes {
+		if !p.Can(auth.Permission(scope)) {
+			return nil, forbidden(fmt.Sprintf("cannot grant scope %s", scope))
+		}
+	}
+
+	secret, hash, err := newAPIKeySecret()
+	if err != nil {
+		return nil, err
+	}
+	k := &model.APIKey{
+		ID:         uuid.New(),
+		Name:       name,
+		SecretHash: hash,
+		Scopes:     strings.Join(scopes, " "),
+		ExpiresAt:  expiresAt,
+		CreatedBy:  &p.UserID,
+	}
+	if err := s.repo.Create(ctx, k); err != nil {
+		return nil, err
+	}
+	return &IssuedAPIKey{APIKey: k, Key: formatAPIKey(k.ID, secret)}, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context) ([]*model.APIKey, error) {
+	return s.repo.List(ctx)
+}
+
+// Rotate заменяет секрет ключа; прежний секрет сразу перестаёт действовать.
+func (s *APIKeyService) Rotate(ctx context.Context, id uuid.UUID) (*IssuedAPIKey, error) {
+	k, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if k.RevokedAt != nil {
+		return nil, invalidState("api key is revoked")
+	}
+
+	secret, hash, err := newAPIKeySecret()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	k.SecretHash = hash
+	k.RotatedAt = &now
+	if err := s.repo.Update(ctx, k); err != nil {
+		return nil, apiKeyNotFound(err)
+	}
+	return &IssuedAPIKey{APIKey: k, Key: formatAPIKey(k.ID, secret)}, nil
+}
+
+// Revoke отзывает ключ. Повторный отзыв ничего не меняет.
+func (s *APIKeyService) Revoke(ctx context.Context, id uuid.UUID) (*model.APIKey, error) {
+	k, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if k.RevokedAt != nil {
+		return k, nil
+	}
+
+	now := time.Now().UTC()
+	k.RevokedAt = &now
+	if err := s.repo.Update(ctx, k); err != nil {
+		return nil, apiKeyNotFound(err)
+	}
+	return k, nil
+}
+
+// Authenticate проверяет ключ из заголовка Authorization и возвращает вызывающего —
+// пользователя, выпустившего ключ, — с разрешениями ключа в его организации. Неверный,
+// просроченный, отозванный или выпущенный без пользователя ключ даёт auth.ErrUnauthorized.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*auth.Principal, error) {
+	id, secret, err := parseAPIKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := s.repo.Lookup(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown api key", auth.ErrUnauthorized)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIKeySecret(secret)), []byte(k.SecretHash)) != 1 {
+		return nil, fmt.Errorf("%w: invalid api key", auth.ErrUnauthorized)
+	}
+	now := time.Now().UTC()
+	if !k.Active(now) {
+		return nil, fmt.Errorf("%w: api key is revoked or expired", auth.ErrUnauthorized)
+	}
+	// ключ действует от имени выпустившего его пользователя: без него не к кому
+	// привязать подписки, которые ключ видит без разрешения read_all
+	if k.CreatedBy == nil {
+		return nil, fmt.Errorf("%w: api key has no owner", auth.ErrUnauthorized)
+	}
+	if err := s.repo.Touch(ctx, k.ID, now); err != nil {
+		return nil, err
+	}
+
+	p := &auth.Principal{UserID: *k.CreatedBy, Subject: "apikey:" + k.ID.String(), TenantID: &k.TenantID}
+	for _, scope := range strings.Fields(k.Scopes) {
+		p.Scopes = append(p.Scopes, auth.Permission(scope))
+	}
+	return p, nil
+}
+
+func (s *APIKeyService) get(ctx context.Context, id uuid.UUID) (*model.APIKey, error) {
+	k, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, apiKeyNotFound(err)
+	}
+	return k, nil
+}
+
+func apiKeyNotFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+// newAPIKeySecret генерирует секрет ключа и его хеш для хранения.
+func newAPIKeySecret() (secret, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret = base64.RawURLEncoding.EncodeToString(b)
+	return secret, hashAPIKeySecret(secret), nil
+}
+
+// hashAPIKeySecret хеширует секрет. Секрет случайный и длинный,
+// поэтому медленная хеш-функция с солью не нужна.
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func formatAPIKey(id uuid.UUID, secret string) string {
+	return apiKeyPrefix + hex.EncodeToString(id[:]) + "_" + secret
+}
+
+func parseAPIKey(key string) (uuid.UUID, string, error) {
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	if !ok {
+		return uuid.Nil, "", fmt.Errorf("%w: malformed api key", auth.ErrUnauthorized)
+	}
+	idHex, secret, ok := strings.Cut(rest, "_")
+	raw, err := hex.DecodeString(idHex)
+	if !ok || err != nil || len(raw) != 16 || secret == "" {
+		return uuid.Nil, "", fmt.Errorf("%w: malformed api key", auth.ErrUnauthorized)
+	}
+	id, _ := uuid.FromBytes(raw)
+	return id, secret, nil
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"subscriptions-go/model"
+	"subscriptions-go/repository"
+)
+
+// AuditService отдаёт журнал изменений подписок.
+type AuditService struct {
+	repo *repository.AuditRepo
+}
+
+func NewAuditService(r *repository.AuditRepo) *AuditService {
+	return &AuditService{repo: r}
+}
+
+// History возвращает журнал изменений подписки, доступной вызывающему.
+func (s *AuditService) History(ctx context.Context, subscriptionID uuid.UUID) ([]*model.AuditEntry, error) {
+	entries, err := s.repo.History(ctx, subscriptionID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return entries, nil
+}
+
+// Search возвращает страницу журнала организации по фильтру.
+func (s *AuditService) Search(ctx context.Context, f repository.AuditFilter) (*repository.AuditPage, error) {
+	return s.repo.Search(ctx, f)
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"subscriptions-go/model"
+	"subscriptions-go/repository"
+)
+
+// MaxBatchSize ограничивает число операций в одном пакете.
+const MaxBatchSize = 100
+
+// Операции пакета.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// BatchOp — операция пакета. ID и Version задаются для update и delete,
+// Subscription — для create и update.
+type BatchOp struct {
+	Op           string
+	ID           uuid.UUID
+	Version      *int64 // ожидаемая версия подписки; nil — без проверки
+	Subscription *model.Subscription
+}
+
+// BatchResult — итог операции пакета: подписка (для delete — nil) или ошибка.
+type BatchResult struct {
+	Subscription *model.Subscription
+	Err          error
+}
+
+// BatchError — ошибка операции с номером Index, из-за которой отменён атомарный пакет.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string { return fmt.Sprintf("operation %d: %v", e.Index, e.Err) }
+
+func (e *BatchError) Unwrap() error { return e.Err }
+
+// batchState — подписки, с которыми сверяются операции пакета: затронутые пакетом
+// и все подписки тех же пользователей на те же сервисы. Обновляется по мере
+// выполнения операций, так что пересечения внутри пакета видны так же, как с базой.
+type batchState map[uuid.UUID]*model.Subscription
+
+func (st batchState) overlap(sub *model.Subscription) *model.Subscription {
+	for _, e := range st {
+		if e.ID != sub.ID && e.UserID == sub.UserID && e.ServiceName == sub.ServiceName && periodsOverlap(sub, e) {
+			return e
+		}
+	}
+	return nil
+}
+
+// Batch выполняет операции по порядку. В атомарном режиме все операции выполняются
+// в одной транзакции, и первая ошибка отменяет пакет: возвращается *BatchError.
+// Иначе каждая операция выполняется отдельно, а её ошибка попадает в результат.
+// Пересечения проверяются с базой и с предыдущими операциями пакета без запроса
+// на каждую операцию.
+func (s *SubscriptionService) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	if len(ops) == 0 || len(ops) > MaxBatchSize {
+		return nil, invalid("operations", fmt.Sprintf("must contain from 1 to %d operations", MaxBatchSize))
+	}
+
+	results := make([]BatchResult, len(ops))
+	for i, op := range ops {
+		if err := s.prepareBatchOp(ctx, op); err != nil {
+			if atomic {
+				return nil, &BatchError{Index: i, Err: err}
+			}
+			results[i].Err = err
+		}
+	}
+
+	state, err := s.loadBatchState(ctx, ops, results)
+	if err != nil {
+		return nil, err
+	}
+
+	run := func(repo *repository.SubscriptionRepo) error {
+		for i, op := range ops {
+			if results[i].Err != nil {
+				continue
+			}
+			sub, err := s.applyBatchOp(ctx, repo, state, op)
+			if err != nil && atomic {
+				return &BatchError{Index: i, Err: err}
+			}
+			results[i] = BatchResult{Subscription: sub, Err: err}
+		}
+		return nil
+	}
+
+	if atomic {
+		err = s.repo.Transaction(ctx, run)
+	} else {
+		err = run(s.repo)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// prepareBatchOp проверяет операцию до обращения к базе.
+func (s *SubscriptionService) prepareBatchOp(ctx context.Context, op BatchOp) error {
+	switch op.Op {
+	case BatchCreate, BatchUpdate:
+		if op.Subscription == nil {
+			return invalid("subscription", "is required")
+		}
+		if op.Op == BatchCreate {
+			// идентификатор нужен заранее, чтобы на подписку можно было сослаться в ошибке пересечения
+			op.Subscription.ID = uuid.New()
+			if err := assignOwner(ctx, op.Subscription); err != nil {
+				return err
+			}
+		} else {
+			op.Subscription.ID = op.ID
+			// без user_id подписка остаётся у текущего владельца: он известен
+			// только после загрузки, см. applyBatchOp
+			if op.Subscription.UserID != uuid.Nil {
+				if err := assignOwner(ctx, op.Subscription); err != nil {
+					return err
+				}
+			}
+		}
+		return s.validate(op.Subscription)
+	case BatchDelete:
+		return nil
+	default:
+		return invalid("op", "must be one of create, update, delete")
+	}
+}
+
+// loadBatchState двумя запросами загружает подписки, нужные для проверки пакета.
+func (s *SubscriptionService) loadBatchState(ctx context.Context, ops []BatchOp, results []BatchResult) (batchState, error) {
+	var ids []uuid.UUID
+	for i, op := range ops {
+		if results[i].Err == nil && op.Op != BatchCreate {
+			ids = append(ids, op.ID)
+		}
+	}
+
+	state := make(batchState)
+	byID, err := s.repo.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range byID {
+		state[sub.ID] = sub
+	}
+
+	var owners []repository.Owner
+	for i, op := range ops {
+		if results[i].Err != nil || op.Subscription == nil {
+			continue
+		}
+		userID := op.Subscription.UserID
+		if current, ok := state[op.ID]; ok && userID == uuid.Nil {
+			userID = current.UserID
+		}
+		if userID != uuid.Nil {
+			owners = append(owners, repository.Owner{UserID: userID, ServiceName: op.Subscription.ServiceName})
+		}
+	}
+	byOwner, err := s.repo.ListByOwners(ctx, owners)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range byOwner {
+		state[sub.ID] = sub
+	}
+	return state, nil
+}
+
+// applyBatchOp выполняет проверенную операцию в репозитории repo и отражает её в state.
+func (s *SubscriptionService) applyBatchOp(ctx context.Context, repo *repository.SubscriptionRepo, state batchState, op BatchOp) (*model.Subscription, error) {
+	sub := op.Subscription
+
+	if op.Op == BatchCreate {
+		if conflict := state.overlap(sub); conflict != nil {
+			return nil, overlapError(sub, &conflict.ID)
+		}
+		if err := repo.Create(ctx, sub); err != nil {
+			if errors.Is(err, repository.ErrOverlap) {
+				return nil, s.overlapError(ctx, sub)
+			}
+			return nil, err
+		}
+		state[sub.ID] = sub
+		return sub, nil
+	}
+
+	current, ok := state[op.ID]
+	if !ok {
+		return nil, notFound(repository.ErrNotFound)
+	}
+	version := current.Version
+	if op.Version != nil {
+		version = *op.Version
+	}
+	if version != current.Version {
+		return nil, ErrPreconditionFailed
+	}
+
+	if op.Op == BatchDelete {
+		if err := repo.Delete(ctx, op.ID, version); err != nil {
+			return nil, precondition(notFound(err))
+		}
+		delete(state, op.ID)
+		return nil, nil
+	}
+
+	if sub.UserID == uuid.Nil {
+		// как и PUT, обновление без user_id не меняет владельца; чужую подписку
+		// по-прежнему может изменить только роль с правом записи для всех
+		sub.UserID = current.UserID
+		if err := assignOwner(ctx, sub); err != nil {
+			return nil, err
+		}
+	}
+	sub.Version = version
+	sub.TenantID, sub.CreatedAt = current.TenantID, current.CreatedAt
+	sub.Prices, sub.Pauses = current.Prices, current.Pauses
+	priceChange := priceChangeFor(current, sub)
+	if conflict := state.overlap(sub); conflict != nil {
+		return nil, overlapError(sub, &conflict.ID)
+	}
+	if err := s.saveUpdate(ctx, repo, sub, priceChange); err != nil {
+		return nil, err
+	}
+	state[sub.ID] = sub
+	return sub, nil
+}
@@ -0,0 +1,21 @@
+package service
+
+import "subscriptions-go/model"
+
+func normalizeBilling(sub *model.Subscription, verr *ValidationError) {
+	if sub.BillingUnit == "" {
+		sub.BillingUnit = model.BillingMonth
+	}
+	if sub.BillingCount == 0 {
+		sub.BillingCount = 1
+	}
+
+	switch sub.BillingUnit {
+	case model.BillingWeek, model.BillingMonth, model.BillingYear:
+	default:
+		verr.Add("billing_unit", "must be one of week, month, year")
+	}
+	if sub.BillingCount < 0 {
+		verr.Add("billing_count", "must be positive")
+	}
+}
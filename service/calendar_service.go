@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/model"
+	"subscriptions-go/repository"
+)
+
+// calendarTokenPrefix отличает токены ленты календаря от других секретов.
+const calendarTokenPrefix = "cal_"
+
+// ErrCalendarTokenNotFound возвращается для неизвестного токена ленты и при отзыве
+// несуществующего токена.
+var ErrCalendarTokenNotFound error = &notFoundError{what: "calendar token"}
+
+// Renewal — расписание продлений подписки: списания First + k * шаг периода
+// по Until включительно, кроме Except.
+type Renewal struct {
+	Subscription *model.Subscription
+	First        time.Time
+	Until        *time.Time                // nil — бессрочно
+	Except       []time.Time               // списания, попавшие на паузы
+	Price        int64                     // цена, действующая сейчас
+	PriceChanges []model.SubscriptionPrice // запланированные изменения цены
+}
+
+type CalendarService struct {
+	tokens *repository.CalendarTokenRepo
+	subs   *repository.SubscriptionRepo
+}
+
+func NewCalendarService(tokens *repository.CalendarTokenRepo, subs *repository.SubscriptionRepo) *CalendarService {
+	return &CalendarService{tokens: tokens, subs: subs}
+}
+
+// IssueToken выпускает токен ленты продлений пользователя userID, заменяя прежний.
+// Токен возвращается только здесь; выпустить токен для другого пользователя может admin.
+func (s *CalendarService) IssueToken(ctx context.Context, userID uuid.UUID) (string, error) {
+	if err := canManageCalendar(ctx, userID); err != nil {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := calendarTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	if err := s.tokens.Save(ctx, &model.CalendarToken{UserID: userID, TokenHash: hashCalendarToken(token)}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokeToken отзывает токен ленты пользователя userID.
+func (s *CalendarService) RevokeToken(ctx context.Context, userID uuid.UUID) error {
+	if err := canManageCalendar(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.tokens.Delete(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrCalendarTokenNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Authenticate проверяет токен ленты пользователя userID и возвращает контекст
+// с его организацией и правом только на чтение своих подписок.
+func (s *CalendarService) Authenticate(ctx context.Context, userID uuid.UUID, token string) (context.Context, error) {
+	if !strings.HasPrefix(token, calendarTokenPrefix) {
+		return nil, ErrCalendarTokenNotFound
+	}
+	t, err := s.tokens.Lookup(ctx, hashCalendarToken(token))
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && t.UserID != userID) {
+		return nil, ErrCalendarTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = auth.WithTenant(ctx, t.TenantID)
+	return auth.WithPrincipal(ctx, &auth.Principal{
+		UserID:   t.UserID,
+		Subject:  "calendar:" + t.UserID.String(),
+		Scopes:   []auth.Permission{auth.PermRead},
+		TenantID: &t.TenantID,
+	}), nil
+}
+
+// Renewals возвращает расписания продлений незакончившихся подписок пользователя.
+func (s *CalendarService) Renewals(ctx context.Context, userID uuid.UUID) ([]Renewal, error) {
+	subs, err := s.subs.List(ctx, &userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].StartDate.Before(subs[j].StartDate) })
+
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+	var renewals []Renewal
+	for _, sub := range subs {
+		r, ok := renewal(sub, now)
+		if !ok || (r.Until != nil && r.Until.Before(today)) {
+			continue
+		}
+		renewals = append(renewals, r)
+	}
+	return renewals, nil
+}
+
+// renewal строит расписание по тем же правилам, что и расчёт сводки: списания
+// идут от StartDate с шагом периода, по месяц EndDate включительно, без пробного
+// периода и пауз. ok == false, если платных списаний нет.
+func renewal(sub *model.Subscription, now time.Time) (Renewal, bool) {
+	r := Renewal{Subscription: sub, Price: sub.PriceAt(now)}
+
+	k := 0
+	if sub.TrialEndDate != nil {
+		for chargeAt(sub, k).Before(*sub.TrialEndDate) {
+			k++
+		}
+	}
+	r.First = chargeAt(sub, k)
+
+	if sub.EndDate != nil {
+		until := sub.EndDate.AddDate(0, 1, -1)
+		r.Until = &until
+	}
+	for _, p := range sub.Pauses {
+		if p.EndDate == nil {
+			// открытая пауза: продлений не будет, пока подписку не возобновят
+			until := p.StartDate.AddDate(0, 0, -1)
+			if r.Until == nil || until.Before(*r.Until) {
+				r.Until = &until
+			}
+			continue
+		}
+		for i := k; chargeAt(sub, i).Before(*p.EndDate); i++ {
+			if at := chargeAt(sub, i); !at.Before(p.StartDate) {
+				r.Except = append(r.Except, at)
+			}
+		}
+	}
+	if r.Until != nil && r.Until.Before(r.First) {
+		return r, false
+	}
+
+	for _, p := range sub.Prices {
+		if p.EffectiveFrom.After(now) {
+			r.PriceChanges = append(r.PriceChanges, p)
+		}
+	}
+	return r, true
+}
+
+// chargeAt возвращает дату k-го списания подписки.
+func chargeAt(sub *model.Subscription, k int) time.Time {
+	n := k * max(sub.BillingCount, 1)
+	switch sub.BillingUnit {
+	case model.BillingWeek:
+		return sub.StartDate.AddDate(0, 0, 7*n)
+	case model.BillingYear:
+		return sub.StartDate.AddDate(n, 0, 0)
+	default:
+		return sub.StartDate.AddDate(0, n, 0)
+	}
+}
+
+// canManageCalendar разрешает управлять лентой пользователя ему самому и admin.
+func canManageCalendar(ctx context.Context, userID uuid.UUID) error {
+	p, ok := auth.FromContext(ctx)
+	if ok && p.UserID != userID && !p.Can(auth.PermWriteAll) {
+		return forbidden("cannot manage the calendar feed of another user")
+	}
+	return nil
+}
+
+func hashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
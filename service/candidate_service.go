@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"subscriptions-go/model"
+	"subscriptions-go/repository"
+	"subscriptions-go/statement"
+)
+
+// ErrCandidateNotFound возвращается, если кандидат в подписки не найден.
+var ErrCandidateNotFound error = &notFoundError{what: "subscription candidate"}
+
+// StatementImport — загружаемая банковская выписка.
+type StatementImport struct {
+	UserID uuid.UUID // владелец счёта; uuid.Nil — вызывающий
+	Format string    // statement.CSV, statement.OFX или statement.CAMT053
+	Body   io.Reader
+	CSV    statement.CSVOptions
+}
+
+// StatementReport — результат разбора выписки.
+type StatementReport struct {
+	Transactions int                            `json:"transactions"`
+	Detected     int                            `json:"detected"`   // найдено регулярных списаний
+	Candidates   []*model.SubscriptionCandidate `json:"candidates"` // ожидающие решения, в том числе найденные раньше
+}
+
+type CandidateService struct {
+	repo *repository.CandidateRepo
+	subs *SubscriptionService
+}
+
+func NewCandidateService(r *repository.CandidateRepo, subs *SubscriptionService) *CandidateService {
+	return &CandidateService{repo: r, subs: subs}
+}
+
+// ImportStatement находит в выписке регулярные списания и сохраняет их кандидатами
+// в подписки. Списания сервисов, на которые у пользователя уже есть подписка,
+// и кандидаты, по которым пользователь уже решил, не предлагаются.
+func (s *CandidateService) ImportStatement(ctx context.Context, in StatementImport) (*StatementReport, error) {
+	userID, err := ownerOf(ctx, in.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	txs, err := statement.Parse(in.Format, in.Body, in.CSV, s.subs.defaultCurrency)
+	var serr *statement.Error
+	if errors.As(err, &serr) {
+		return nil, invalid(serr.Field, serr.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	detected := statement.Detect(txs)
+	report := &StatementReport{Transactions: len(txs), Detected: len(detected), Candidates: []*model.SubscriptionCandidate{}}
+	if len(detected) == 0 {
+		return report, nil
+	}
+
+	subs, err := s.subs.repo.List(ctx, &userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range detected {
+		if subscribed(subs, d.Key) {
+			continue
+		}
+		c := &model.SubscriptionCandidate{
+			UserID:         userID,
+			MerchantKey:    d.Key,
+			ServiceName:    d.Merchant,
+			Price:          d.Amount,
+			Currency:       d.Currency,
+			BillingUnit:    d.BillingUnit,
+			BillingCount:   d.BillingCount,
+			StartDate:      monthStart(d.First),
+			LastChargeDate: d.Last,
+			Occurrences:    d.Occurrences,
+			Status:         model.CandidatePending,
+		}
+		if d.Ended {
+			end := monthStart(d.Last)
+			c.EndDate = &end
+		}
+		if err := s.repo.Save(ctx, c); err != nil {
+			return nil, err
+		}
+		if c.Status == model.CandidatePending {
+			report.Candidates = append(report.Candidates, c)
+		}
+	}
+	return report, nil
+}
+
+// subscribed сообщает, есть ли среди subs подписка на сервис получателя key:
+// "Yandex Plus" совпадает и с "YANDEX PLUS MOSCOW", и с "Yandex".
+func subscribed(subs []*model.Subscription, key string) bool {
+	for _, sub := range subs {
+		k := statement.MerchantKey(sub.ServiceName)
+		if k != "" && (k == key || strings.HasPrefix(key, k+" ") || strings.HasPrefix(k, key+" ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// List возвращает кандидатов в состоянии status (пусто — в любом).
+func (s *CandidateService) List(ctx context.Context, userID *uuid.UUID, status string) ([]*model.SubscriptionCandidate, error) {
+	switch status {
+	case "", model.CandidatePending, model.CandidateAccepted, model.CandidateDismissed:
+	default:
+		return nil, invalid("status", "must be one of pending, accepted, dismissed")
+	}
+	return s.repo.List(ctx, userID, status)
+}
+
+// Accept создаёт подписку по кандидату и отмечает его принятым в одной транзакции.
+func (s *CandidateService) Accept(ctx context.Context, id uuid.UUID) (*model.Subscription, error) {
+	c, err := s.pending(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &model.Subscription{
+		ServiceName:  c.ServiceName,
+		Price:        c.Price,
+		Currency:     c.Currency,
+		UserID:       c.UserID,
+		StartDate:    c.StartDate,
+		EndDate:      c.EndDate,
+		BillingUnit:  c.BillingUnit,
+		BillingCount: c.BillingCount,
+	}
+	// подписка создаётся вместе с решением по кандидату: если кандидата одновременно
+	// приняли или отклонили, подписка не остаётся
+	err = s.repo.Transaction(ctx, func(tx *repository.CandidateRepo, subs *repository.SubscriptionRepo) error {
+		if err := s.subs.create(ctx, subs, sub); err != nil {
+			return err
+		}
+		c.Status, c.SubscriptionID = model.CandidateAccepted, &sub.ID
+		return decide(ctx, tx, c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Dismiss отклоняет кандидата; повторный импорт его больше не предложит.
+func (s *CandidateService) Dismiss(ctx context.Context, id uuid.UUID) (*model.SubscriptionCandidate, error) {
+	c, err := s.pending(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ownerOf(ctx, c.UserID); err != nil {
+		return nil, err
+	}
+
+	c.Status = model.CandidateDismissed
+	if err := decide(ctx, s.repo, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// pending возвращает кандидата, по которому ещё не принято решение.
+func (s *CandidateService) pending(ctx context.Context, id uuid.UUID) (*model.SubscriptionCandidate, error) {
+	c, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrCandidateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != model.CandidatePending {
+		return nil, invalidState("candidate is already %s", c.Status)
+	}
+	return c, nil
+}
+
+func decide(ctx context.Context, repo *repository.CandidateRepo, c *model.SubscriptionCandidate) error {
+	err := repo.Decide(ctx, c)
+	if errors.Is(err, repository.ErrAlreadyDecided) {
+		return invalidState("candidate has already been decided")
+	}
+	return err
+}
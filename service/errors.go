@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"subscriptions-go/repository"
+)
+
+// Коды ошибок, которые API отдаёт клиентам.
+const (
+	CodeNotFound         = "not_found"
+	CodeValidationFailed = "validation_failed"
+	CodeOverlap          = "subscription_overlap"
+	CodeInvalidState     = "invalid_state"
+	CodeForbidden        = "forbidden"
+	CodePrecondition     = "precondition_failed"
+	CodeTenantExists     = "tenant_exists"
+)
+
+// ErrNotFound возвращается, если подписка не найдена.
+var ErrNotFound = errors.New("subscription not found")
+
+// ErrOverlap возвращается (через ConflictError), если период подписки пересекается
+// с другой подпиской того же пользователя на тот же сервис.
+var ErrOverlap = errors.New("subscription overlaps with existing subscription")
+
+// ErrPreconditionFailed возвращается, если подписка изменилась с версии, указанной вызывающим.
+var ErrPreconditionFailed = errors.New("subscription has been modified")
+
+// ErrForbidden возвращается, если вызывающему не разрешена операция.
+var ErrForbidden = errors.New("forbidden")
+
+// notFoundError — отсутствие записи, отличной от подписки; errors.Is(err, ErrNotFound) для неё истинно.
+type notFoundError struct {
+	what string
+}
+
+func (e *notFoundError) Error() string { return e.what + " not found" }
+
+func (e *notFoundError) Is(target error) bool { return target == ErrNotFound }
+
+// FieldError — ошибка в значении одного поля.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError — входные данные не прошли проверку.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Add добавляет ошибку поля.
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// OrNil возвращает nil, если ошибок полей нет.
+func (e *ValidationError) OrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func invalid(field, message string) error {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+// ConflictError — операция конфликтует с текущим состоянием данных.
+// Для пересечения подписок Code равен CodeOverlap, а ConflictingID указывает
+// на пересекающуюся подписку, если её удалось определить.
+type ConflictError struct {
+	Code          string
+	Message       string
+	ConflictingID *uuid.UUID
+}
+
+func (e *ConflictError) Error() string { return e.Message }
+
+func (e *ConflictError) Unwrap() error {
+	if e.Code == CodeOverlap {
+		return ErrOverlap
+	}
+	return nil
+}
+
+func invalidState(format string, args ...interface{}) error {
+	return &ConflictError{Code: CodeInvalidState, Message: fmt.Sprintf(format, args...)}
+}
+
+// ForbiddenError — операция не разрешена вызывающему.
+type ForbiddenError struct {
+	Message string
+}
+
+func (e *ForbiddenError) Error() string { return e.Message }
+
+func (e *ForbiddenError) Unwrap() error { return ErrForbidden }
+
+func forbidden(message string) error {
+	return &ForbiddenError{Message: message}
+}
+
+// precondition переводит устаревшую версию подписки в ErrPreconditionFailed.
+func precondition(err error) error {
+	if errors.Is(err, repository.ErrVersionMismatch) {
+		return ErrPreconditionFailed
+	}
+	return err
+}
+
+// notFound переводит отсутствие записи в репозитории в ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"subscriptions-go/model"
+	"subscriptions-go/repository"
+)
+
+// MaxImportRows ограничивает число подписок в одном импорте.
+const MaxImportRows = 5000
+
+// Import создаёт подписки из импорта. Подписки, не прошедшие проверку или
+// пересекающиеся с базой либо с предыдущими строками импорта, попадают в отчёт
+// с ошибкой и пропускаются; остальные записываются в одной транзакции.
+// При dryRun ничего не записывается, а отчёт показывает, что сделал бы импорт.
+// Если запись не удалась, импорт отменяется целиком и возвращается *BatchError.
+func (s *SubscriptionService) Import(ctx context.Context, subs []*model.Subscription, dryRun bool) ([]BatchResult, error) {
+	if len(subs) == 0 || len(subs) > MaxImportRows {
+		return nil, invalid("rows", fmt.Sprintf("must contain from 1 to %d subscriptions", MaxImportRows))
+	}
+
+	ops := make([]BatchOp, len(subs))
+	results := make([]BatchResult, len(subs))
+	for i, sub := range subs {
+		ops[i] = BatchOp{Op: BatchCreate, Subscription: sub}
+		results[i].Err = s.prepareBatchOp(ctx, ops[i])
+	}
+
+	state, err := s.loadBatchState(ctx, ops, results)
+	if err != nil {
+		return nil, err
+	}
+	// пересечения проверяются до записи, чтобы пробный запуск и импорт давали один отчёт
+	for i, sub := range subs {
+		if results[i].Err != nil {
+			continue
+		}
+		if conflict := state.overlap(sub); conflict != nil {
+			results[i].Err = overlapError(sub, &conflict.ID)
+			continue
+		}
+		state[sub.ID] = sub
+		results[i].Subscription = sub
+	}
+	if dryRun {
+		return results, nil
+	}
+
+	err = s.repo.Transaction(ctx, func(tx *repository.SubscriptionRepo) error {
+		for i, res := range results {
+			if res.Err != nil {
+				continue
+			}
+			if err := tx.Create(ctx, res.Subscription); err != nil {
+				if errors.Is(err, repository.ErrOverlap) {
+					err = s.overlapError(ctx, res.Subscription)
+				}
+				return &BatchError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
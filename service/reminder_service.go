@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"subscriptions-go/auth"
+	"subscriptions-go/model"
+	"subscriptions-go/money"
+	"subscriptions-go/notify"
+	"subscriptions-go/repository"
+)
+
+// maxReminderDays ограничивает, за сколько дней пользователь может попросить напоминать.
+const maxReminderDays = 60
+
+// reminderSendTimeout — сколько ждать доставки одного напоминания.
+const reminderSendTimeout = 30 * time.Second
+
+// reminderClaimLease — через сколько отметку о напоминании без подтверждённой доставки
+// можно забрать: её оставил процесс, упавший между отметкой и отправкой.
+const reminderClaimLease = 10 * time.Minute
+
+// ErrNotificationPreferenceNotFound возвращается, если пользователь не настроил напоминания.
+var ErrNotificationPreferenceNotFound error = &notFoundError{what: "notification preferences"}
+
+type ReminderService struct {
+	repo     *repository.NotificationRepo
+	subs     *repository.SubscriptionRepo
+	notifier notify.Notifier
+	days     int // окно напоминаний по умолчанию, дней
+}
+
+// NewReminderService создаёт сервис напоминаний. window — за сколько до события
+// напоминать пользователям, не задавшим своё значение; округляется до дней.
+func NewReminderService(r *repository.NotificationRepo, subs *repository.SubscriptionRepo, notifier notify.Notifier, window time.Duration) *ReminderService {
+	days := int((window + 24*time.Hour - 1) / (24 * time.Hour))
+	return &ReminderService{repo: r, subs: subs, notifier: notifier, days: days}
+}
+
+// GetPreference возвращает настройки напоминаний пользователя userID.
+func (s *ReminderService) GetPreference(ctx context.Context, userID uuid.UUID) (*model.NotificationPreference, error) {
+	if scope := auth.ScopeUserID(ctx); scope != nil && *scope != userID {
+		return nil, forbidden("cannot read notification preferences of another user")
+	}
+	p, err := s.repo.GetPreference(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotificationPreferenceNotFound
+	}
+	return p, err
+}
+
+// SavePreference сохраняет настройки напоминаний; чужие настройки может менять только admin.
+func (s *ReminderService) SavePreference(ctx context.Context, p *model.NotificationPreference) error {
+	owner, err := ownerOf(ctx, p.UserID)
+	if err != nil {
+		return err
+	}
+	p.UserID = owner
+
+	verr := &ValidationError{}
+	p.Email = strings.TrimSpace(p.Email)
+	if addr, err := mail.ParseAddress(p.Email); err != nil || addr.Address != p.Email {
+		verr.Add("email", "must be an email address")
+	}
+	if p.DaysBefore < 0 || p.DaysBefore > maxReminderDays {
+		verr.Add("days_before", fmt.Sprintf("must be between 0 and %d", maxReminderDays))
+	}
+	if err := verr.OrNil(); err != nil {
+		return err
+	}
+	return s.repo.SavePreference(ctx, p)
+}
+
+// reminder — событие подписки, о котором нужно напомнить.
+type reminder struct {
+	sub   *model.Subscription
+	kind  string
+	date  time.Time
+	price int64 // для продления — сумма списания
+}
+
+// SendDue отправляет напоминания о продлениях и окончаниях подписок, до которых
+// осталось не больше окна напоминаний пользователя. Каждое напоминание отправляется
+// один раз; не доставленное будет отправлено при следующем вызове. Ошибки по одному
+// пользователю не мешают напоминать остальным. Возвращает число отправленных напоминаний.
+func (s *ReminderService) SendDue(ctx context.Context, now time.Time) (int, error) {
+	prefs, err := s.repo.ListSubscribed(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	sent := 0
+	var errs []error
+	for _, p := range prefs {
+		n, err := s.remind(ctx, p, today)
+		sent += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", p.UserID, err))
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+func (s *ReminderService) remind(ctx context.Context, p *model.NotificationPreference, today time.Time) (int, error) {
+	subs, err := s.subs.List(auth.WithTenant(ctx, p.TenantID), &p.UserID, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	days := p.DaysBefore
+	if days == 0 {
+		days = s.days
+	}
+	horizon := today.AddDate(0, 0, days)
+
+	var due []reminder
+	for _, sub := range subs {
+		due = append(due, dueReminders(sub, p, today, horizon)...)
+	}
+	return s.deliver(ctx, p, due)
+}
+
+// deliver отправляет пользователю p напоминания due. Если напоминание не удалось
+// отправить, отметка с него снимается и deliver переходит к следующему.
+func (s *ReminderService) deliver(ctx context.Context, p *model.NotificationPreference, due []reminder) (int, error) {
+	sent := 0
+	var errs []error
+	for _, r := range due {
+		// Отметка ставится до отправки: так два экземпляра сервера не отправят
+		// одно напоминание дважды. Если процесс упадёт между отметкой и отправкой,
+		// через reminderClaimLease напоминание заберёт следующий запуск.
+		entry := &model.ReminderLog{
+			SubscriptionID: r.sub.ID,
+			Kind:           r.kind,
+			DueDate:        r.date,
+			TenantID:       p.TenantID,
+			UserID:         p.UserID,
+		}
+		claimed, err := s.repo.Claim(ctx, entry, reminderClaimLease)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, reminderSendTimeout)
+		err = s.notifier.Send(sendCtx, r.message(p.Email))
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s reminder for subscription %s: %w", r.kind, r.sub.ID, errors.Join(err, s.repo.Release(ctx, entry))))
+			continue
+		}
+		sent++
+		if err := s.repo.MarkSent(ctx, entry); err != nil {
+			// письмо ушло, но без отметки его отправят ещё раз после reminderClaimLease
+			errs = append(errs, err)
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// dueReminders возвращает события подписки с today по horizon включительно,
+// о которых пользователь просил напоминать.
+func dueReminders(sub *model.Subscription, p *model.NotificationPreference, today, horizon time.Time) []reminder {
+	var due []reminder
+	if p.RenewalReminders {
+		if r, ok := renewal(sub, today); ok {
+			if d, ok := nextCharge(sub, r, today); ok && !d.After(horizon) {
+				due = append(due, reminder{sub: sub, kind: model.ReminderRenewal, date: d, price: sub.PriceAt(d)})
+			}
+		}
+	}
+	if p.EndingReminders && sub.EndDate != nil {
+		// подписка действует по последний день месяца EndDate
+		end := sub.EndDate.AddDate(0, 1, -1)
+		if !end.Before(today) && !end.After(horizon) {
+			due = append(due, reminder{sub: sub, kind: model.ReminderEnding, date: end})
+		}
+	}
+	return due
+}
+
+// nextCharge возвращает первое списание по расписанию r не раньше from.
+func nextCharge(sub *model.Subscription, r Renewal, from time.Time) (time.Time, bool) {
+	for k := 0; ; k++ {
+		d := chargeAt(sub, k)
+		if d.Before(r.First) || d.Before(from) || containsDate(r.Except, d) {
+			continue
+		}
+		if r.Until != nil && d.After(*r.Until) {
+			return time.Time{}, false
+		}
+		return d, true
+	}
+}
+
+func containsDate(dates []time.Time, d time.Time) bool {
+	for _, x := range dates {
+		if x.Equal(d) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r reminder) message(to string) notify.Message {
+	date := r.date.Format("2006-01-02")
+	m := notify.Message{To: to}
+	switch r.kind {
+	case model.ReminderRenewal:
+		m.Subject = fmt.Sprintf("%s renews on %s", r.sub.ServiceName, date)
+		m.Body = fmt.Sprintf("Your %s subscription renews on %s.\nAmount: %s.\n",
+			r.sub.ServiceName, date, money.Format(r.price, r.sub.Currency))
+	case model.ReminderEnding:
+		m.Subject = fmt.Sprintf("%s ends on %s", r.sub.ServiceName, date)
+		m.Body = fmt.Sprintf("Your %s subscription ends on %s and will not renew after that.\n",
+			r.sub.ServiceName, date)
+	}
+	m.Body += "\nYou can change or turn off these reminders in your notification preferences.\n"
+	return m
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"subscriptions-go/model"
+	"subscriptions-go/notify"
+	"subscriptions-go/repository"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func dayPtr(s string) *time.Time {
+	t := day(s)
+	return &t
+}
+
+func TestNextCharge(t *testing.T) {
+	monthly := &model.Subscription{StartDate: day("2024-01-01"), BillingUnit: model.BillingMonth, BillingCount: 1}
+	quarterly := &model.Subscription{StartDate: day("2024-01-01"), BillingUnit: model.BillingMonth, BillingCount: 3}
+	weekly := &model.Subscription{StartDate: day("2024-01-01"), BillingUnit: model.BillingWeek, BillingCount: 1}
+	yearly := &model.Subscription{StartDate: day("2023-06-01"), BillingUnit: model.BillingYear, BillingCount: 1}
+
+	tests := []struct {
+		name string
+		sub  *model.Subscription
+		r    Renewal
+		from string
+		want string // пусто — списаний больше нет
+	}{
+		{"monthly", monthly, Renewal{First: day("2024-01-01")}, "2024-03-10", "2024-04-01"},
+		{"charge on from", monthly, Renewal{First: day("2024-01-01")}, "2024-04-01", "2024-04-01"},
+		{"every three months", quarterly, Renewal{First: day("2024-01-01")}, "2024-02-05", "2024-04-01"},
+		{"weekly", weekly, Renewal{First: day("2024-01-01")}, "2024-01-10", "2024-01-15"},
+		{"yearly", yearly, Renewal{First: day("2023-06-01")}, "2024-03-10", "2024-06-01"},
+		{"after trial", monthly, Renewal{First: day("2024-06-01")}, "2024-03-10", "2024-06-01"},
+		{"paused charge skipped", monthly, Renewal{First: day("2024-01-01"), Except: []time.Time{day("2024-04-01")}}, "2024-03-10", "2024-05-01"},
+		{"until last charge", monthly, Renewal{First: day("2024-01-01"), Until: dayPtr("2024-04-30")}, "2024-03-10", "2024-04-01"},
+		{"ended", monthly, Renewal{First: day("2024-01-01"), Until: dayPtr("2024-03-31")}, "2024-03-10", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := nextCharge(tt.sub, tt.r, day(tt.from))
+			if tt.want == "" {
+				if ok {
+					t.Errorf("nextCharge() = %s, want no charge", got.Format("2006-01-02"))
+				}
+				return
+			}
+			if !ok || !got.Equal(day(tt.want)) {
+				t.Errorf("nextCharge() = %s, %v, want %s", got.Format("2006-01-02"), ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDueReminders(t *testing.T) {
+	both := &model.NotificationPreference{RenewalReminders: true, EndingReminders: true}
+	today, horizon := day("2024-03-28"), day("2024-04-04")
+
+	tests := []struct {
+		name string
+		sub  model.Subscription
+		pref *model.NotificationPreference
+		want []string // вид, дата и сумма напоминаний
+	}{
+		{
+			name: "renewal within window",
+			sub:  model.Subscription{StartDate: day("2024-01-01"), Price: 999},
+			pref: both,
+			want: []string{"renewal 2024-04-01 999"},
+		},
+		{
+			name: "renewal at new price",
+			sub: model.Subscription{StartDate: day("2024-01-01"), Price: 999,
+				Prices: []model.SubscriptionPrice{{Price: 1299, EffectiveFrom: day("2024-04-01")}}},
+			pref: both,
+			want: []string{"renewal 2024-04-01 1299"},
+		},
+		{
+			name: "renewal reminders disabled",
+			sub:  model.Subscription{StartDate: day("2024-01-01"), Price: 999},
+			pref: &model.NotificationPreference{EndingReminders: true},
+			want: nil,
+		},
+		{
+			name: "renewal beyond window",
+			sub:  model.Subscription{StartDate: day("2024-01-01"), BillingUnit: model.BillingYear, Price: 999},
+			pref: both,
+			want: nil,
+		},
+		{
+			name: "ending instead of renewal",
+			sub:  model.Subscription{StartDate: day("2024-01-01"), EndDate: dayPtr("2024-03-01"), Price: 999},
+			pref: both,
+			want: []string{"ending 2024-03-31 0"},
+		},
+		{
+			name: "ending reminders disabled",
+			sub:  model.Subscription{StartDate: day("2024-01-01"), EndDate: dayPtr("2024-03-01"), Price: 999},
+			pref: &model.NotificationPreference{RenewalReminders: true},
+			want: nil,
+		},
+		{
+			name: "ending beyond window",
+			sub:  model.Subscription{StartDate: day("2024-01-01"), EndDate: dayPtr("2024-04-01"), Price: 999},
+			pref: both,
+			want: []string{"renewal 2024-04-01 999"},
+		},
+		{
+			name: "open pause",
+			sub: model.Subscription{StartDate: day("2024-01-01"), Price: 999,
+				Pauses: []model.SubscriptionPause{{StartDate: day("2024-03-01")}}},
+			pref: both,
+			want: nil,
+		},
+		{
+			name: "first charge after trial",
+			sub:  model.Subscription{StartDate: day("2024-01-01"), TrialEndDate: dayPtr("2024-04-01"), Price: 999},
+			pref: both,
+			want: []string{"renewal 2024-04-01 999"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range dueReminders(&tt.sub, tt.pref, today, horizon) {
+				if r.sub != &tt.sub {
+					t.Errorf("reminder refers to another subscription")
+				}
+				got = append(got, fmt.Sprintf("%s %s %d", r.kind, r.date.Format("2006-01-02"), r.price))
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("dueReminders() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeNotifier запоминает сообщения и не доставляет те, чья тема есть в fail.
+type fakeNotifier struct {
+	fail map[string]bool
+	sent []notify.Message
+}
+
+func (n *fakeNotifier) Send(ctx context.Context, m notify.Message) error {
+	n.sent = append(n.sent, m)
+	if n.fail[m.Subject] {
+		return errors.New("mail server unavailable")
+	}
+	return nil
+}
+
+// reminderLogRepo возвращает репозиторий напоминаний без базы. Отметки подписок
+// из taken уже стоят; операции с журналом записываются в ops.
+func reminderLogRepo(t *testing.T, ops *[]string, taken ...uuid.UUID) *repository.NotificationRepo {
+	t.Helper()
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscriptionOf := func(tx *gorm.DB) uuid.UUID {
+		if l, ok := tx.Statement.Dest.(*model.ReminderLog); ok && l.SubscriptionID != uuid.Nil {
+			return l.SubscriptionID
+		}
+		for _, v := range tx.Statement.Vars {
+			if id, ok := v.(uuid.UUID); ok {
+				return id
+			}
+		}
+		return uuid.Nil
+	}
+	record := func(op string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			id := subscriptionOf(tx)
+			if op == "claim" {
+				for _, t := range taken {
+					if t == id {
+						*ops = append(*ops, "skip "+id.String())
+						return
+					}
+				}
+				tx.RowsAffected = 1
+			}
+			*ops = append(*ops, op+" "+id.String())
+		}
+	}
+	for _, err := range []error{
+		db.Callback().Create().After("gorm:create").Register("test:claim", record("claim")),
+		db.Callback().Update().After("gorm:update").Register("test:sent", record("sent")),
+		db.Callback().Delete().After("gorm:delete").Register("test:release", record("release")),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repository.NewNotificationRepo(db)
+}
+
+func TestDeliverContinuesAfterSendError(t *testing.T) {
+	failing := &model.Subscription{ID: uuid.New(), ServiceName: "Netflix", Currency: "USD"}
+	ok := &model.Subscription{ID: uuid.New(), ServiceName: "Spotify", Currency: "USD"}
+	taken := &model.Subscription{ID: uuid.New(), ServiceName: "YouTube", Currency: "USD"}
+	due := []reminder{
+		{sub: failing, kind: model.ReminderRenewal, date: day("2024-04-01"), price: 1549},
+		{sub: ok, kind: model.ReminderEnding, date: day("2024-04-30")},
+		{sub: taken, kind: model.ReminderRenewal, date: day("2024-04-01"), price: 299},
+	}
+
+	var ops []string
+	notifier := &fakeNotifier{fail: map[string]bool{"Netflix renews on 2024-04-01": true}}
+	s := NewReminderService(reminderLogRepo(t, &ops, taken.ID), nil, notifier, 24*time.Hour)
+	pref := &model.NotificationPreference{TenantID: uuid.New(), UserID: uuid.New(), Email: "user@example.com"}
+
+	sent, err := s.deliver(context.Background(), pref, due)
+	if sent != 1 {
+		t.Errorf("sent = %d, want 1", sent)
+	}
+	if err == nil || !strings.Contains(err.Error(), failing.ID.String()) {
+		t.Errorf("error = %v, want the failed reminder of %s", err, failing.ID)
+	}
+
+	wantOps := []string{
+		"claim " + failing.ID.String(), "release " + failing.ID.String(),
+		"claim " + ok.ID.String(), "sent " + ok.ID.String(),
+		"skip " + taken.ID.String(),
+	}
+	if strings.Join(ops, "\n") != strings.Join(wantOps, "\n") {
+		t.Errorf("reminder log operations:\n%s\nwant:\n%s", strings.Join(ops, "\n"), strings.Join(wantOps, "\n"))
+	}
+
+	var subjects []string
+	for _, m := range notifier.sent {
+		if m.To != pref.Email {
+			t.Errorf("message to %q, want %q", m.To, pref.Email)
+		}
+		subjects = append(subjects, m.Subject)
+	}
+	wantSubjects := []string{"Netflix renews on 2024-04-01", "Spotify ends on 2024-04-30"}
+	if strings.Join(subjects, "\n") != strings.Join(wantSubjects, "\n") {
+		t.Errorf("sent %q, want %q", subjects, wantSubjects)
+	}
+}
@@ -1,156 +1,427 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
 
+	"subscriptions-go/auth"
 	"subscriptions-go/model"
+	"subscriptions-go/money"
 	"subscriptions-go/repository"
 
 	"github.com/google/uuid"
 )
 
 type SubscriptionService struct {
-	repo *repository.SubscriptionRepo
+	repo            *repository.SubscriptionRepo
+	rates           money.RateProvider
+	defaultCurrency string
 }
 
-func NewSubscriptionService(r *repository.SubscriptionRepo) *SubscriptionService {
-	return &SubscriptionService{repo: r}
+func NewSubscriptionService(r *repository.SubscriptionRepo, rates money.RateProvider, defaultCurrency string) *SubscriptionService {
+	return &SubscriptionService{repo: r, rates: rates, defaultCurrency: money.Normalize(defaultCurrency)}
 }
 
-func (s *SubscriptionService) GetByID(id uuid.UUID) (*model.Subscription, error) {
-	return s.repo.GetByID(id)
+func (s *SubscriptionService) GetByID(ctx context.Context, id uuid.UUID) (*model.Subscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return sub, nil
 }
 
-func (s *SubscriptionService) Create(sub *model.Subscription) error {
-	if sub.Price < 0 {
-		return errors.New("price cannot be negative")
+func (s *SubscriptionService) Create(ctx context.Context, sub *model.Subscription) error {
+	return s.create(ctx, s.repo, sub)
+}
+
+// create проверяет и создаёт подписку через репозиторий repo, например внутри
+// транзакции вызывающего.
+func (s *SubscriptionService) create(ctx context.Context, repo *repository.SubscriptionRepo, sub *model.Subscription) error {
+	if err := assignOwner(ctx, sub); err != nil {
+		return err
+	}
+	if err := s.validate(sub); err != nil {
+		return err
 	}
 
-	sub.StartDate = time.Date(sub.StartDate.Year(), sub.StartDate.Month(), 1, 0, 0, 0, 0, sub.StartDate.Location())
-	if sub.EndDate != nil {
-		ed := time.Date(sub.EndDate.Year(), sub.EndDate.Month(), 1, 0, 0, 0, 0, sub.EndDate.Location())
-		sub.EndDate = &ed
+	if err := s.checkOverlap(ctx, sub); err != nil {
+		return err
+	}
+
+	if err := repo.Create(ctx, sub); err != nil {
+		if errors.Is(err, repository.ErrOverlap) {
+			return s.overlapError(ctx, sub)
+		}
+		return err
 	}
+	return nil
+}
 
-	existing, err := s.repo.List(&sub.UserID, &sub.ServiceName)
+func (s *SubscriptionService) Update(ctx context.Context, sub *model.Subscription) error {
+	if err := assignOwner(ctx, sub); err != nil {
+		return err
+	}
+	if err := s.validate(sub); err != nil {
+		return err
+	}
+
+	current, err := s.GetByID(ctx, sub.ID)
 	if err != nil {
 		return err
 	}
+	if current.Version != sub.Version {
+		return ErrPreconditionFailed
+	}
 
-	for _, e := range existing {
-		eEnd := e.EndDate
-		if eEnd == nil {
-			temp := time.Date(9999, 12, 1, 0, 0, 0, 0, time.UTC)
-			eEnd = &temp
-		}
-		newEnd := sub.EndDate
-		if newEnd == nil {
-			temp := time.Date(9999, 12, 1, 0, 0, 0, 0, time.UTC)
-			newEnd = &temp
-		}
+	priceChange := priceChangeFor(current, sub)
 
-		if sub.StartDate.Before(*eEnd) && (*newEnd).After(e.StartDate) {
-			return fmt.Errorf("subscription for service %s overlaps with existing subscription", sub.ServiceName)
+	// Период не менялся — пересечений не прибавилось; смену сервиса или владельца
+	// без смены дат всё равно отсечёт ограничение в базе.
+	if datesChanged(current, sub) {
+		if err := s.checkOverlap(ctx, sub); err != nil {
+			return err
 		}
 	}
 
-	return s.repo.Create(sub)
+	return s.saveUpdate(ctx, s.repo, sub, priceChange)
 }
 
-func (s *SubscriptionService) Update(sub *model.Subscription) error {
-	if sub.Price < 0 {
-		return errors.New("price cannot be negative")
+// priceChangeFor готовит изменение цены при обновлении подписки. Изменение цены
+// начавшейся подписки не переписывает прошлые месяцы: новая цена действует
+// с текущего месяца, а в sub.Price остаётся начальная; действующую цену
+// вызывающий видит в CurrentPrice.
+func priceChangeFor(current, sub *model.Subscription) *model.SubscriptionPrice {
+	now := monthStart(time.Now().UTC())
+	if !current.StartDate.Before(now) {
+		return nil
 	}
 
-	sub.StartDate = time.Date(sub.StartDate.Year(), sub.StartDate.Month(), 1, 0, 0, 0, 0, sub.StartDate.Location())
-	if sub.EndDate != nil {
-		ed := time.Date(sub.EndDate.Year(), sub.EndDate.Month(), 1, 0, 0, 0, 0, sub.EndDate.Location())
-		sub.EndDate = &ed
+	var change *model.SubscriptionPrice
+	if sub.Price != current.PriceAt(now) {
+		change = &model.SubscriptionPrice{SubscriptionID: sub.ID, Price: sub.Price, EffectiveFrom: now}
 	}
+	sub.Price = current.Price
+	return change
+}
 
-	existing, err := s.repo.List(&sub.UserID, &sub.ServiceName)
+// saveUpdate записывает обновлённую подписку и изменение цены в одной транзакции
+// репозитория repo. После изменения цены sub перечитывается вместе с историей.
+func (s *SubscriptionService) saveUpdate(ctx context.Context, repo *repository.SubscriptionRepo, sub *model.Subscription, priceChange *model.SubscriptionPrice) error {
+	err := repo.Transaction(ctx, func(tx *repository.SubscriptionRepo) error {
+		if err := tx.Update(ctx, sub); err != nil {
+			return err
+		}
+		if priceChange != nil {
+			return tx.SavePrice(ctx, priceChange)
+		}
+		return nil
+	})
+	if errors.Is(err, repository.ErrOverlap) {
+		return s.overlapError(ctx, sub)
+	}
 	if err != nil {
-		return err
+		return precondition(notFound(err))
 	}
 
-	for _, e := range existing {
-		if e.ID == sub.ID {
-			continue
+	if priceChange != nil {
+		updated, err := repo.GetByID(ctx, sub.ID)
+		if err != nil {
+			return err
 		}
-		eEnd := e.EndDate
-		if eEnd == nil {
-			temp := time.Date(9999, 12, 1, 0, 0, 0, 0, time.UTC)
-			eEnd = &temp
+		*sub = *updated
+	}
+	return nil
+}
+
+// SchedulePriceChange задаёт новую цену подписки начиная с месяца effectiveFrom.
+func (s *SubscriptionService) SchedulePriceChange(ctx context.Context, id uuid.UUID, price int64, effectiveFrom time.Time) (*model.Subscription, error) {
+	if price < 0 {
+		return nil, invalid("price", "must not be negative")
+	}
+	effectiveFrom = monthStart(effectiveFrom)
+
+	sub, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !effectiveFrom.After(sub.StartDate) {
+		return nil, invalid("effective_from", "must be after start_date")
+	}
+	if sub.EndDate != nil && effectiveFrom.After(*sub.EndDate) {
+		return nil, invalid("effective_from", "must not be after end_date")
+	}
+
+	if err := s.repo.SavePrice(ctx, &model.SubscriptionPrice{
+		SubscriptionID: id,
+		Price:          price,
+		EffectiveFrom:  effectiveFrom,
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// Pause приостанавливает подписку начиная с месяца from.
+func (s *SubscriptionService) Pause(ctx context.Context, id uuid.UUID, from time.Time) (*model.Subscription, error) {
+	from = monthStart(from)
+
+	sub, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.OpenPause() != nil {
+		return nil, invalidState("subscription is already paused")
+	}
+	if from.Before(sub.StartDate) {
+		return nil, invalid("from", "pause cannot start before start_date")
+	}
+	if sub.EndDate != nil && from.After(*sub.EndDate) {
+		return nil, invalid("from", "pause cannot start after end_date")
+	}
+	for _, p := range sub.Pauses {
+		if p.EndDate != nil && from.Before(*p.EndDate) {
+			return nil, invalid("from", "pause overlaps a previous pause")
 		}
-		newEnd := sub.EndDate
-		if newEnd == nil {
-			temp := time.Date(9999, 12, 1, 0, 0, 0, 0, time.UTC)
-			newEnd = &temp
+	}
+
+	if err := s.repo.SavePause(ctx, &model.SubscriptionPause{SubscriptionID: id, StartDate: from}); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// Resume возобновляет приостановленную подписку; from — первый снова оплачиваемый месяц.
+// Если пауза ещё не вступила в силу (from совпадает с её началом), она удаляется.
+func (s *SubscriptionService) Resume(ctx context.Context, id uuid.UUID, from time.Time) (*model.Subscription, error) {
+	from = monthStart(from)
+
+	sub, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	pause := sub.OpenPause()
+	if pause == nil {
+		return nil, invalidState("subscription is not paused")
+	}
+	if from.Before(pause.StartDate) {
+		return nil, invalid("from", "resume cannot be before pause start")
+	}
+
+	if from.Equal(pause.StartDate) {
+		err = s.repo.DeletePause(ctx, pause)
+	} else {
+		pause.EndDate = &from
+		err = s.repo.SavePause(ctx, pause)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// Delete помечает подписку удалённой; её можно восстановить через Restore до очистки.
+// Если version задана, подписка удаляется, только пока её версия не изменилась.
+func (s *SubscriptionService) Delete(ctx context.Context, id uuid.UUID, version *int64) error {
+	if version == nil {
+		current, err := s.GetByID(ctx, id)
+		if err != nil {
+			return err
 		}
+		version = &current.Version
+	}
+	return precondition(notFound(s.repo.Delete(ctx, id, *version)))
+}
+
+// Restore восстанавливает удалённую подписку, если её период не пересекается
+// с подписками, созданными после удаления.
+func (s *SubscriptionService) Restore(ctx context.Context, id uuid.UUID) (*model.Subscription, error) {
+	sub, err := s.repo.GetDeleted(ctx, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := s.checkOverlap(ctx, sub); err != nil {
+		return nil, err
+	}
 
-		if sub.StartDate.Before(*eEnd) && (*newEnd).After(e.StartDate) {
-			return fmt.Errorf("subscription for service %s overlaps with existing subscription", sub.ServiceName)
+	if err := s.repo.Restore(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrOverlap) {
+			return nil, s.overlapError(ctx, sub)
 		}
+		return nil, notFound(err)
 	}
+	return s.GetByID(ctx, id)
+}
 
-	return s.repo.Update(sub)
+// PurgeDeleted окончательно удаляет подписки, удалённые больше retention назад.
+func (s *SubscriptionService) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.repo.PurgeDeleted(ctx, time.Now().Add(-retention))
 }
 
-func (s *SubscriptionService) Delete(id uuid.UUID) error {
-	return s.repo.Delete(id)
+// Search возвращает страницу подписок по фильтру.
+func (s *SubscriptionService) Search(ctx context.Context, f repository.ListFilter) (*repository.Page, error) {
+	return s.repo.Search(ctx, f)
 }
 
-func (s *SubscriptionService) List(userID *uuid.UUID, serviceName *string) ([]*model.Subscription, error) {
-	return s.repo.List(userID, serviceName)
+// TrialsConverting возвращает подписки, которые перейдут с пробного периода на платный в ближайшие days дней.
+func (s *SubscriptionService) TrialsConverting(ctx context.Context, days int, userID *uuid.UUID) ([]*model.Subscription, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	return s.repo.ListTrialsEnding(ctx, today, today.AddDate(0, 0, days), userID)
 }
 
-func (s *SubscriptionService) Sum(periodStart, periodEnd time.Time, userID *uuid.UUID, serviceName *string) (int64, error) {
-	ps := time.Date(periodStart.Year(), periodStart.Month(), 1, 0, 0, 0, 0, time.UTC)
-	pe := time.Date(periodEnd.Year(), periodEnd.Month(), 1, 0, 0, 0, 0, time.UTC)
+// checkOverlap проверяет, что период подписки не пересекается с другими подписками
+// пользователя на тот же сервис. Окончательно это гарантирует ограничение
+// subscriptions_no_overlap в базе, проверка здесь даёт понятную ошибку заранее.
+func (s *SubscriptionService) checkOverlap(ctx context.Context, sub *model.Subscription) error {
+	conflict, err := s.findOverlap(ctx, sub)
+	if err != nil {
+		return err
+	}
+	if conflict != nil {
+		return overlapError(sub, &conflict.ID)
+	}
+	return nil
+}
 
-	subs, err := s.repo.List(userID, serviceName)
+// datesChanged сообщает, изменился ли период подписки.
+func datesChanged(current, sub *model.Subscription) bool {
+	if !current.StartDate.Equal(sub.StartDate) {
+		return true
+	}
+	if current.EndDate == nil || sub.EndDate == nil {
+		return current.EndDate != sub.EndDate
+	}
+	return !current.EndDate.Equal(*sub.EndDate)
+}
+
+func (s *SubscriptionService) findOverlap(ctx context.Context, sub *model.Subscription) (*model.Subscription, error) {
+	existing, err := s.repo.List(ctx, &sub.UserID, &sub.ServiceName)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	var total int64
-	for _, sub := range subs {
-		start := sub.StartDate
-		end := sub.EndDate
-		if end == nil || end.After(pe) {
-			end = &pe
-		}
-		if start.After(pe) || end.Before(ps) {
-			continue
+	for _, e := range existing {
+		if e.ID != sub.ID && periodsOverlap(sub, e) {
+			return e, nil
 		}
+	}
+	return nil, nil
+}
 
-		overlapStart := maxTime(&start, &ps)
-		overlapEnd := minTime(end, &pe)
-		months := monthsBetween(overlapStart, overlapEnd)
-		total += int64(months) * int64(sub.Price)
+func periodsOverlap(a, b *model.Subscription) bool {
+	return a.StartDate.Before(periodEnd(b)) && b.StartDate.Before(periodEnd(a))
+}
+
+// overlapError строит ошибку для пересечения, обнаруженного ограничением в базе
+// (например, при параллельной записи), и пытается найти пересекающуюся подписку.
+func (s *SubscriptionService) overlapError(ctx context.Context, sub *model.Subscription) error {
+	var conflictingID *uuid.UUID
+	if conflict, err := s.findOverlap(ctx, sub); err == nil && conflict != nil {
+		conflictingID = &conflict.ID
+	}
+	return overlapError(sub, conflictingID)
+}
+
+func overlapError(sub *model.Subscription, conflictingID *uuid.UUID) error {
+	return &ConflictError{
+		Code:          CodeOverlap,
+		Message:       fmt.Sprintf("subscription for service %s overlaps with existing subscription", sub.ServiceName),
+		ConflictingID: conflictingID,
 	}
+}
+
+// periodEnd возвращает конец периода подписки (не включительно): месяц EndDate оплачивается.
+func periodEnd(sub *model.Subscription) time.Time {
+	if sub.EndDate == nil {
+		return time.Date(9999, 12, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return sub.EndDate.AddDate(0, 1, 0)
+}
 
-	return total, nil
+// assignOwner привязывает подписку к вызывающему: без user_id подписка становится его,
+// подписку другого пользователя создать или передать может только admin.
+func assignOwner(ctx context.Context, sub *model.Subscription) error {
+	owner, err := ownerOf(ctx, sub.UserID)
+	if err != nil {
+		return err
+	}
+	sub.UserID = owner
+	return nil
+}
+
+// ownerOf возвращает пользователя, от имени которого вызывающий изменяет данные:
+// userID или, если он не указан, самого вызывающего.
+func ownerOf(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		if userID == uuid.Nil {
+			return uuid.Nil, invalid("user_id", "is required")
+		}
+		return userID, nil
+	}
+	if userID == uuid.Nil {
+		return p.UserID, nil
+	}
+	if userID != p.UserID && !p.Can(auth.PermWriteAll) {
+		return uuid.Nil, forbidden("cannot manage subscriptions of another user")
+	}
+	return userID, nil
 }
 
-func maxTime(a, b *time.Time) *time.Time {
-	if a.After(*b) {
-		return a
+// validate проверяет подписку перед записью и приводит даты к первому числу месяца.
+// Возвращает ValidationError со всеми найденными ошибками полей.
+func (s *SubscriptionService) validate(sub *model.Subscription) error {
+	verr := &ValidationError{}
+
+	if sub.Price < 0 {
+		verr.Add("price", "must not be negative")
 	}
-	return b
+	s.normalizeCurrency(sub, verr)
+	normalizeBilling(sub, verr)
+
+	sub.StartDate = monthStart(sub.StartDate)
+	if sub.EndDate != nil {
+		ed := monthStart(*sub.EndDate)
+		sub.EndDate = &ed
+		if ed.Before(sub.StartDate) {
+			verr.Add("end_date", "must not be before start_date")
+		}
+	}
+	if sub.TrialEndDate != nil {
+		te := monthStart(*sub.TrialEndDate)
+		sub.TrialEndDate = &te
+		if te.Before(sub.StartDate) {
+			verr.Add("trial_end_date", "must not be before start_date")
+		}
+	}
+
+	return verr.OrNil()
 }
 
-func minTime(a, b *time.Time) *time.Time {
-	if a.Before(*b) {
-		return a
+func (s *SubscriptionService) normalizeCurrency(sub *model.Subscription, verr *ValidationError) {
+	if sub.Currency == "" {
+		sub.Currency = s.defaultCurrency
 	}
-	return b
+	sub.Currency = money.Normalize(sub.Currency)
+	switch {
+	case !money.Valid(sub.Currency):
+		verr.Add("currency", "must be a known ISO 4217 code")
+	case !s.convertible(sub.Currency):
+		verr.Add("currency", "no exchange rate for "+sub.Currency)
+	}
+}
+
+// convertible сообщает, есть ли курс валюты к валюте по умолчанию: без него
+// подписку в этой валюте не учесть в сводке.
+func (s *SubscriptionService) convertible(currency string) bool {
+	_, err := s.rates.Rate(currency, s.defaultCurrency)
+	return err == nil
 }
 
-func monthsBetween(start, end *time.Time) int {
-	yearDiff := end.Year() - start.Year()
-	monthDiff := int(end.Month()) - int(start.Month())
-	return yearDiff*12 + monthDiff + 1
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
 }
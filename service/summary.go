@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"subscriptions-go/money"
+	"subscriptions-go/repository"
+)
+
+// Поля, по которым можно группировать сводку.
+const (
+	GroupByService = "service_name"
+	GroupByUser    = "user_id"
+	GroupByMonth   = "month"
+)
+
+// SummaryQuery — параметры расчёта сводки.
+type SummaryQuery struct {
+	PeriodStart time.Time // первый месяц периода
+	PeriodEnd   time.Time // последний месяц периода, включительно
+	UserID      *uuid.UUID
+	ServiceName *string
+	Currency    string   // валюта отчёта, по умолчанию валюта сервиса
+	GroupBy     []string // уровни вложенности разбивки, например service_name, month
+}
+
+// CurrencyTotal — сумма по подпискам в одной валюте и её эквивалент в валюте отчёта.
+type CurrencyTotal struct {
+	Currency  string `json:"currency"`
+	Total     int64  `json:"total"`
+	Converted int64  `json:"converted"`
+}
+
+// SummaryGroup — промежуточный итог по одному значению поля группировки.
+// Groups содержит разбивку по следующему полю из SummaryQuery.GroupBy.
+type SummaryGroup struct {
+	Field  string         `json:"field"`
+	Value  string         `json:"value"`
+	Total  int64          `json:"total"`
+	Groups []SummaryGroup `json:"groups,omitempty"`
+}
+
+// Summary — итог по подпискам за период в валюте Currency.
+type Summary struct {
+	Currency   string          `json:"currency"`
+	Total      int64           `json:"total"`
+	ByCurrency []CurrencyTotal `json:"by_currency"`
+	GroupBy    []string        `json:"group_by,omitempty"`
+	Groups     []SummaryGroup  `json:"groups,omitempty"`
+}
+
+// ValidGroupBy проверяет список полей группировки.
+func ValidGroupBy(fields []string) error {
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		switch f {
+		case GroupByService, GroupByUser, GroupByMonth:
+		default:
+			return invalid("group_by", fmt.Sprintf("unknown field %q", f))
+		}
+		if seen[f] {
+			return invalid("group_by", fmt.Sprintf("duplicate field %q", f))
+		}
+		seen[f] = true
+	}
+	return nil
+}
+
+// summaryLine — сумма списаний с одинаковыми значениями полей группировки в одной валюте.
+type summaryLine struct {
+	values   []string
+	currency string
+	amount   int64
+}
+
+func (s *SubscriptionService) Summary(ctx context.Context, q SummaryQuery) (*Summary, error) {
+	currency := q.Currency
+	if currency == "" {
+		currency = s.defaultCurrency
+	}
+	currency = money.Normalize(currency)
+	if !money.Valid(currency) {
+		return nil, invalid("currency", "must be a known ISO 4217 code")
+	}
+	if !s.convertible(currency) {
+		return nil, invalid("currency", "no exchange rate for "+currency)
+	}
+	if err := ValidGroupBy(q.GroupBy); err != nil {
+		return nil, err
+	}
+
+	ps := monthStart(q.PeriodStart)
+	// конец периода — первое число месяца, следующего за последним месяцем отчёта
+	pe := monthStart(q.PeriodEnd).AddDate(0, 1, 0)
+
+	f := repository.ChargeFilter{
+		PeriodStart: ps,
+		PeriodEnd:   pe,
+		UserID:      q.UserID,
+		ServiceName: q.ServiceName,
+	}
+	for _, field := range q.GroupBy {
+		switch field {
+		case GroupByService:
+			f.GroupByService = true
+		case GroupByUser:
+			f.GroupByUser = true
+		case GroupByMonth:
+			f.GroupByMonth = true
+		}
+	}
+
+	rows, err := s.repo.SumCharges(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]*summaryLine, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, &summaryLine{
+			values:   groupValues(row, q.GroupBy),
+			currency: row.Currency,
+			amount:   row.Amount,
+		})
+	}
+
+	return s.buildSummary(lines, currency, q.GroupBy)
+}
+
+// buildSummary переводит строки в валюту отчёта и собирает итоги.
+// Конвертируется каждая строка, а итоги складываются из уже переведённых сумм,
+// поэтому промежуточные итоги всегда сходятся с общим.
+func (s *SubscriptionService) buildSummary(lines []*summaryLine, currency string, groupBy []string) (*Summary, error) {
+	sort.Slice(lines, func(i, j int) bool {
+		for k := range lines[i].values {
+			if lines[i].values[k] != lines[j].values[k] {
+				return lines[i].values[k] < lines[j].values[k]
+			}
+		}
+		return lines[i].currency < lines[j].currency
+	})
+
+	res := &Summary{Currency: currency, ByCurrency: []CurrencyTotal{}, GroupBy: groupBy}
+	byCurrency := make(map[string]*CurrencyTotal)
+	for _, line := range lines {
+		converted, err := money.Convert(line.amount, line.currency, currency, s.rates)
+		if err != nil {
+			return nil, err
+		}
+
+		ct, ok := byCurrency[line.currency]
+		if !ok {
+			ct = &CurrencyTotal{Currency: line.currency}
+			byCurrency[line.currency] = ct
+		}
+		ct.Total += line.amount
+		ct.Converted += converted
+		res.Total += converted
+
+		groups := &res.Groups
+		for k, field := range groupBy {
+			value := formatGroupValue(field, line.values[k])
+			n := len(*groups)
+			if n == 0 || (*groups)[n-1].Value != value {
+				*groups = append(*groups, SummaryGroup{Field: field, Value: value})
+				n++
+			}
+			g := &(*groups)[n-1]
+			g.Total += converted
+			groups = &g.Groups
+		}
+	}
+
+	for _, ct := range byCurrency {
+		res.ByCurrency = append(res.ByCurrency, *ct)
+	}
+	sort.Slice(res.ByCurrency, func(i, j int) bool { return res.ByCurrency[i].Currency < res.ByCurrency[j].Currency })
+
+	return res, nil
+}
+
+func groupValues(row repository.ChargeTotal, groupBy []string) []string {
+	values := make([]string, len(groupBy))
+	for i, field := range groupBy {
+		switch field {
+		case GroupByService:
+			values[i] = row.ServiceName
+		case GroupByUser:
+			values[i] = row.UserID
+		case GroupByMonth:
+			values[i] = row.Month // YYYY-MM, сортируется хронологически
+		}
+	}
+	return values
+}
+
+// formatGroupValue приводит значение к формату API (месяцы — MM-YYYY).
+func formatGroupValue(field, value string) string {
+	if field != GroupByMonth {
+		return value
+	}
+	t, err := time.Parse("2006-01", value)
+	if err != nil {
+		return value
+	}
+	return t.Format("01-2006")
+}
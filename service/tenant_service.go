@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"subscriptions-go/model"
+	"subscriptions-go/repository"
+)
+
+// TenantService заводит организации. Доступен только администраторам платформы.
+type TenantService struct {
+	repo *repository.TenantRepo
+}
+
+func NewTenantService(r *repository.TenantRepo) *TenantService {
+	return &TenantService{repo: r}
+}
+
+// Create заводит организацию с уникальным именем name.
+func (s *TenantService) Create(ctx context.Context, name string) (*model.Tenant, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return nil, invalid("name", "is required")
+	case utf8.RuneCountInString(name) > 200:
+		return nil, invalid("name", "must be at most 200 characters")
+	}
+
+	t := &model.Tenant{Name: name}
+	err := s.repo.Create(ctx, t)
+	if errors.Is(err, repository.ErrTenantExists) {
+		return nil, &ConflictError{Code: CodeTenantExists, Message: "tenant " + name + " already exists"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *TenantService) List(ctx context.Context) ([]*model.Tenant, error) {
+	return s.repo.List(ctx)
+}
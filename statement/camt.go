@@ -0,0 +1,151 @@
+package statement
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"subscriptions-go/money"
+)
+
+// Выписка ISO 20022 camt.053. Пространство имён не указано, поэтому подходят все
+// версии схемы (camt.053.001.02 и новее).
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtEntry struct {
+	Amount    camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Booking   camtDate   `xml:"BookgDt"`
+	Value     camtDate   `xml:"ValDt"`
+	Info      string     `xml:"AddtlNtryInf"`
+	Details   []camtTx   `xml:"NtryDtls>TxDtls"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtTx struct {
+	Amount       camtAmount `xml:"Amt"`
+	TxAmount     camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	Creditor     string     `xml:"RltdPties>Cdtr>Nm"`
+	CreditorPty  string     `xml:"RltdPties>Cdtr>Pty>Nm"` // camt.053.001.08 и новее
+	Unstructured []string   `xml:"RmtInf>Ustrd"`
+}
+
+// ParseCAMT053 читает операции выписки camt.053.
+func ParseCAMT053(r io.Reader) ([]Transaction, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		var serr *xml.SyntaxError
+		if errors.As(err, &serr) || errors.Is(err, io.EOF) {
+			return nil, &Error{Field: "file", Message: "invalid camt.053 XML: " + err.Error()}
+		}
+		return nil, err
+	}
+	if len(doc.Statements) == 0 {
+		return nil, &Error{Field: "file", Message: "not a camt.053 statement"}
+	}
+
+	var txs []Transaction
+	for i, stmt := range doc.Statements {
+		for j, e := range stmt.Entries {
+			entry, err := camtTransactions(e)
+			if err != nil {
+				return nil, &Error{Field: "file", Message: "statement " + strconv.Itoa(i+1) + ", entry " + strconv.Itoa(j+1) + ": " + err.Error()}
+			}
+			txs = append(txs, entry...)
+		}
+	}
+	return txs, nil
+}
+
+// camtTransactions разбирает запись выписки. Пакетная запись с суммами по каждой
+// операции даёт несколько операций.
+func camtTransactions(e camtEntry) ([]Transaction, error) {
+	date, err := e.Booking.parse()
+	if err != nil {
+		if date, err = e.Value.parse(); err != nil {
+			return nil, err
+		}
+	}
+
+	parts := []camtAmount{e.Amount}
+	if len(e.Details) > 1 {
+		parts = parts[:0]
+		for _, d := range e.Details {
+			a := d.Amount
+			if a.Value == "" {
+				a = d.TxAmount
+			}
+			if a.Value == "" {
+				parts = []camtAmount{e.Amount} // сумм по операциям нет — берём запись целиком
+				break
+			}
+			parts = append(parts, a)
+		}
+	}
+
+	txs := make([]Transaction, 0, len(parts))
+	for i, a := range parts {
+		currency := money.Normalize(a.Currency)
+		if currency == "" {
+			currency = money.Normalize(e.Amount.Currency)
+		}
+		amount, err := parseDecimal(a.Value, currency)
+		if err != nil {
+			return nil, err
+		}
+		if e.CdtDbtInd == "DBIT" {
+			amount = -amount
+		}
+
+		merchant := e.Info
+		if i < len(e.Details) {
+			merchant = e.Details[i].merchant(merchant)
+		}
+		txs = append(txs, Transaction{Date: date, Amount: amount, Currency: currency, Merchant: merchant})
+	}
+	return txs, nil
+}
+
+func (t camtTx) merchant(fallback string) string {
+	switch {
+	case t.Creditor != "":
+		return t.Creditor
+	case t.CreditorPty != "":
+		return t.CreditorPty
+	case len(t.Unstructured) > 0:
+		return t.Unstructured[0]
+	}
+	return fallback
+}
+
+func (d camtDate) parse() (time.Time, error) {
+	v := d.Date
+	if v == "" {
+		v = d.DateTime
+	}
+	if len(v) < 10 {
+		return time.Time{}, &Error{Message: "booking date is missing"}
+	}
+	t, err := time.Parse("2006-01-02", v[:10])
+	if err != nil {
+		return time.Time{}, &Error{Message: "invalid booking date " + v}
+	}
+	return t, nil
+}
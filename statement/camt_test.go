@@ -0,0 +1,86 @@
+package statement
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const camt053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Ntry>
+        <Amt Ccy="EUR">12.99</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <BookgDt><Dt>2024-03-01</Dt></BookgDt>
+        <NtryDtls><TxDtls>
+          <RltdPties><Cdtr><Nm>Disney Plus</Nm></Cdtr></RltdPties>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">1000.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <ValDt><DtTm>2024-03-02T09:30:00</DtTm></ValDt>
+        <AddtlNtryInf>Refund</AddtlNtryInf>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">20.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <BookgDt><Dt>2024-03-03</Dt></BookgDt>
+        <AddtlNtryInf>Batch</AddtlNtryInf>
+        <NtryDtls>
+          <TxDtls>
+            <Amt Ccy="EUR">5.00</Amt>
+            <RltdPties><Cdtr><Pty><Nm>Dropbox</Nm></Pty></Cdtr></RltdPties>
+          </TxDtls>
+          <TxDtls>
+            <AmtDtls><TxAmt><Amt Ccy="EUR">15.00</Amt></TxAmt></AmtDtls>
+            <RmtInf><Ustrd>GITHUB SPONSORS</Ustrd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+func TestParseCAMT053(t *testing.T) {
+	got, err := ParseCAMT053(strings.NewReader(camt053))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Transaction{
+		{Date: date("2024-03-01"), Amount: -1299, Currency: "EUR", Merchant: "Disney Plus"},
+		{Date: date("2024-03-02"), Amount: 100000, Currency: "EUR", Merchant: "Refund"},
+		{Date: date("2024-03-03"), Amount: -500, Currency: "EUR", Merchant: "Dropbox"},
+		{Date: date("2024-03-03"), Amount: -1500, Currency: "EUR", Merchant: "GITHUB SPONSORS"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseCAMT053Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"empty", ""},
+		{"broken XML", "<Document><BkToCstmrStmt>"},
+		{"not a statement", "<Document><CstmrCdtTrfInitn/></Document>"},
+		{"missing date", "<Document><BkToCstmrStmt><Stmt><Ntry><Amt Ccy=\"EUR\">1.00</Amt></Ntry></Stmt></BkToCstmrStmt></Document>"},
+		{"invalid amount", "<Document><BkToCstmrStmt><Stmt><Ntry><Amt Ccy=\"EUR\">1,000.00</Amt>" +
+			"<BookgDt><Dt>2024-03-01</Dt></BookgDt></Ntry></Stmt></BkToCstmrStmt></Document>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCAMT053(strings.NewReader(tt.in))
+			var serr *Error
+			if !errors.As(err, &serr) || serr.Field != "file" {
+				t.Errorf("error = %v, want *Error for file", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package statement
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"subscriptions-go/money"
+)
+
+// csvColumns — поля операции в CSV; по умолчанию столбец называется так же, как поле.
+var csvColumns = []string{"date", "amount", "currency", "merchant"}
+
+// requiredCSVColumns должны быть в файле обязательно.
+var requiredCSVColumns = map[string]bool{"date": true, "amount": true, "merchant": true}
+
+// DateFormats — поддерживаемые форматы дат CSV и соответствующие раскладки time.
+var DateFormats = map[string]string{
+	"YYYY-MM-DD": "2006-01-02",
+	"DD.MM.YYYY": "02.01.2006",
+	"DD/MM/YYYY": "02/01/2006",
+	"MM/DD/YYYY": "01/02/2006",
+	"DD-MM-YYYY": "02-01-2006",
+	"YYYYMMDD":   "20060102",
+}
+
+// autoDateLayouts пробуются по очереди, если формат дат не задан. Форматы с косой
+// чертой неоднозначны и угадываться не будут.
+var autoDateLayouts = []string{"2006-01-02", "02.01.2006", "20060102"}
+
+// CSVOptions описывает выгрузку конкретного банка.
+type CSVOptions struct {
+	Comma      rune              // разделитель полей; 0 — запятая
+	Columns    map[string]string // поле -> заголовок столбца
+	DateFormat string            // ключ DateFormats; пусто — определить автоматически
+	Currency   string            // валюта строк без столбца currency
+	// PositiveDebits — в выписке списания записаны положительными суммами, а
+	// поступлений нет (так выгружают, например, отчёты по карте).
+	PositiveDebits bool
+}
+
+// ParseCSV читает операции из CSV с заголовком.
+func ParseCSV(r io.Reader, opts CSVOptions) ([]Transaction, error) {
+	layouts := autoDateLayouts
+	if opts.DateFormat != "" {
+		layout, ok := DateFormats[opts.DateFormat]
+		if !ok {
+			return nil, &Error{Field: "date_format", Message: "must be one of " + strings.Join(dateFormatNames(), ", ")}
+		}
+		layouts = []string{layout}
+	}
+
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, &Error{Field: "file", Message: "must start with a header row"}
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+	columns, err := csvIndex(header, opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	var txs []Transaction
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		line, _ := cr.FieldPos(0)
+		if blank(record) {
+			continue
+		}
+
+		value := func(field string) string {
+			i, ok := columns[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		t := Transaction{Merchant: value("merchant"), Currency: money.Normalize(value("currency"))}
+		if t.Currency == "" {
+			t.Currency = money.Normalize(opts.Currency)
+		}
+		if t.Date, err = parseDate(value("date"), layouts); err != nil {
+			return nil, &Error{Line: line, Field: "date", Message: err.Error()}
+		}
+		if t.Amount, err = parseAmount(value("amount"), t.Currency); err != nil {
+			return nil, &Error{Line: line, Field: "amount", Message: err.Error()}
+		}
+		if opts.PositiveDebits {
+			t.Amount = -t.Amount
+		}
+		txs = append(txs, t)
+	}
+	return txs, nil
+}
+
+// csvIndex сопоставляет поля операции с номерами столбцов по заголовку и mapping.
+func csvIndex(header []string, mapping map[string]string) (map[string]int, error) {
+	for field := range mapping {
+		if _, ok := requiredCSVColumns[field]; !ok && field != "currency" {
+			return nil, &Error{Field: "columns[" + field + "]", Message: "is not a statement field"}
+		}
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // BOM из Excel
+		}
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	columns := make(map[string]int)
+	for _, field := range csvColumns {
+		name := field
+		if m, ok := mapping[field]; ok {
+			name = m
+		}
+		i, ok := positions[strings.ToLower(strings.TrimSpace(name))]
+		switch {
+		case ok:
+			columns[field] = i
+		case requiredCSVColumns[field] || mapping[field] != "":
+			return nil, &Error{Field: "columns[" + field + "]", Message: fmt.Sprintf("column %q not found in header", name)}
+		}
+	}
+	return columns, nil
+}
+
+func parseDate(v string, layouts []string) (time.Time, error) {
+	// время операции, если банк его выгружает, не нужно
+	if i := strings.IndexAny(v, " T"); i > 0 {
+		v = v[:i]
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", v)
+}
+
+func csvError(err error) error {
+	var perr *csv.ParseError
+	if errors.As(err, &perr) {
+		return &Error{Line: perr.Line, Field: "file", Message: "invalid CSV: " + perr.Err.Error()}
+	}
+	return err
+}
+
+func dateFormatNames() []string {
+	names := make([]string, 0, len(DateFormats))
+	for name := range DateFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func blank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package statement
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts CSVOptions
+		want []Transaction
+	}{
+		{
+			name: "default columns",
+			in: "date,amount,currency,merchant\n" +
+				"2024-01-05,-9.99,usd,NETFLIX.COM\n" +
+				"2024-01-06,1500.00,USD,Salary\n",
+			want: []Transaction{
+				{Date: date("2024-01-05"), Amount: -999, Currency: "USD", Merchant: "NETFLIX.COM"},
+				{Date: date("2024-01-06"), Amount: 150000, Currency: "USD", Merchant: "Salary"},
+			},
+		},
+		{
+			name: "mapped columns, semicolon, european amounts",
+			in: "\ufeffДата;Сумма;Описание\n" +
+				"05.01.2024;-1 299,00;Яндекс Плюс\n" +
+				"\n" +
+				"05.02.2024 10:15;-1.299,00;Яндекс Плюс\n",
+			opts: CSVOptions{
+				Comma:    ';',
+				Columns:  map[string]string{"date": "Дата", "amount": "Сумма", "merchant": "Описание"},
+				Currency: "rub",
+			},
+			want: []Transaction{
+				{Date: date("2024-01-05"), Amount: -129900, Currency: "RUB", Merchant: "Яндекс Плюс"},
+				{Date: date("2024-02-05"), Amount: -129900, Currency: "RUB", Merchant: "Яндекс Плюс"},
+			},
+		},
+		{
+			name: "explicit date format, thousands separator",
+			in: "Date,Amount,Merchant\n" +
+				"01/31/2024,\"1,000.00\",Rent\n",
+			opts: CSVOptions{DateFormat: "MM/DD/YYYY", Currency: "USD", PositiveDebits: true},
+			want: []Transaction{
+				{Date: date("2024-01-31"), Amount: -100000, Currency: "USD", Merchant: "Rent"},
+			},
+		},
+		{
+			name: "header only",
+			in:   "date,amount,merchant\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCSV(strings.NewReader(tt.in), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		opts      CSVOptions
+		wantLine  int
+		wantField string
+	}{
+		{"empty file", "", CSVOptions{}, 0, "file"},
+		{"missing column", "date,amount\n2024-01-05,-1\n", CSVOptions{}, 0, "columns[merchant]"},
+		{"unknown mapped field", "date,amount,merchant\n", CSVOptions{Columns: map[string]string{"note": "Note"}}, 0, "columns[note]"},
+		{"unknown date format", "date,amount,merchant\n", CSVOptions{DateFormat: "YY"}, 0, "date_format"},
+		{"ambiguous date", "date,amount,merchant\n01/02/2024,-1,Shop\n", CSVOptions{}, 2, "date"},
+		{"invalid amount", "date,amount,merchant\n2024-01-05,-1,Shop\n2024-01-06,ten,Shop\n", CSVOptions{Currency: "USD"}, 3, "amount"},
+		{"ambiguous amount", "date,amount,merchant\n2024-01-05,-12.345,Shop\n", CSVOptions{Currency: "USD"}, 2, "amount"},
+		{"too many decimals", "date,amount,merchant\n2024-01-05,-1.9999,Shop\n", CSVOptions{Currency: "USD"}, 2, "amount"},
+		{"broken quotes", "date,amount,merchant\n2024-01-05,-1,\"Shop\n", CSVOptions{}, 2, "file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCSV(strings.NewReader(tt.in), tt.opts)
+			var serr *Error
+			if !errors.As(err, &serr) {
+				t.Fatalf("error = %v, want *Error", err)
+			}
+			if serr.Line != tt.wantLine || serr.Field != tt.wantField {
+				t.Errorf("error at line %d, field %s (%v), want line %d, field %s",
+					serr.Line, serr.Field, serr, tt.wantLine, tt.wantField)
+			}
+		})
+	}
+}
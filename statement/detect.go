@@ -0,0 +1,203 @@
+package statement
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"subscriptions-go/model"
+)
+
+// Candidate — регулярное списание, найденное в выписке.
+type Candidate struct {
+	Key          string // нормализованное имя получателя, см. MerchantKey
+	Merchant     string
+	Amount       int64 // сумма последнего списания, положительная
+	Currency     string
+	BillingUnit  string
+	BillingCount int
+	First        time.Time
+	Last         time.Time
+	Occurrences  int
+	// Ended — после последнего списания прошло больше двух периодов до конца выписки:
+	// подписку, похоже, уже отменили.
+	Ended bool
+}
+
+// period — распознаваемый период списаний и допустимый разброс интервала в днях.
+type period struct {
+	unit      string
+	count     int
+	days      int
+	min, max  int
+	minCharge int // сколько списаний нужно, чтобы считать их регулярными
+}
+
+var periods = []period{
+	{model.BillingWeek, 1, 7, 6, 8, 4},
+	{model.BillingMonth, 1, 30, 26, 35, 3},
+	{model.BillingMonth, 3, 91, 85, 97, 3},
+	{model.BillingYear, 1, 365, 350, 380, 2},
+}
+
+const (
+	// minRegularShare — доля интервалов и сумм, которые должны укладываться в период
+	// и разброс суммы: один пропущенный или повторённый платёж не мешает распознаванию.
+	minRegularShare = 0.75
+	// amountTolerance — допустимое отклонение суммы списания от медианной.
+	amountTolerance = 0.2
+)
+
+// noiseWords не отличают одного получателя от другого.
+var noiseWords = map[string]bool{
+	"www": true, "com": true, "net": true, "org": true, "inc": true, "llc": true,
+	"ltd": true, "gmbh": true, "ooo": true, "pos": true, "payment": true, "purchase": true,
+}
+
+// MerchantKey нормализует имя получателя, чтобы списания одного сервиса с разными
+// номерами операций и городами попадали в одну группу: "NETFLIX.COM 866-579 CA"
+// и "Netflix.com" дают "netflix".
+func MerchantKey(name string) string {
+	words := merchantWords(name)
+	if len(words) > 2 {
+		words = words[:2]
+	}
+	return strings.ToLower(strings.Join(words, " "))
+}
+
+// merchantWords возвращает значимые слова имени получателя: без чисел, кодов
+// операций, доменов и слов короче трёх букв.
+func merchantWords(name string) []string {
+	var words []string
+	for _, w := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(w)) < 3 || noiseWords[strings.ToLower(w)] || strings.IndexFunc(w, unicode.IsDigit) >= 0 {
+			continue
+		}
+		words = append(words, w)
+	}
+	return words
+}
+
+// Detect группирует списания по получателю и валюте и возвращает группы,
+// повторяющиеся с постоянным периодом и примерно одной суммой.
+func Detect(txs []Transaction) []Candidate {
+	type group struct {
+		key, currency string
+		txs           []Transaction
+	}
+	groups := map[[2]string]*group{}
+	var end time.Time
+	for _, t := range txs {
+		if t.Date.After(end) {
+			end = t.Date
+		}
+		key := MerchantKey(t.Merchant)
+		if t.Amount >= 0 || key == "" {
+			continue
+		}
+		g := groups[[2]string{key, t.Currency}]
+		if g == nil {
+			g = &group{key: key, currency: t.Currency}
+			groups[[2]string{key, t.Currency}] = g
+		}
+		g.txs = append(g.txs, t)
+	}
+
+	var candidates []Candidate
+	for _, g := range groups {
+		sort.SliceStable(g.txs, func(i, j int) bool { return g.txs[i].Date.Before(g.txs[j].Date) })
+		p, ok := detectPeriod(g.txs)
+		if !ok || !stableAmounts(g.txs) {
+			continue
+		}
+		first, last := g.txs[0], g.txs[len(g.txs)-1]
+		candidates = append(candidates, Candidate{
+			Key:          g.key,
+			Merchant:     merchantName(g.txs),
+			Amount:       -last.Amount,
+			Currency:     g.currency,
+			BillingUnit:  p.unit,
+			BillingCount: p.count,
+			First:        first.Date,
+			Last:         last.Date,
+			Occurrences:  len(g.txs),
+			Ended:        end.Sub(last.Date) > time.Duration(2*p.days)*24*time.Hour,
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Key != candidates[j].Key {
+			return candidates[i].Key < candidates[j].Key
+		}
+		return candidates[i].Currency < candidates[j].Currency
+	})
+	return candidates
+}
+
+// detectPeriod подбирает период по медианному интервалу между списаниями.
+func detectPeriod(txs []Transaction) (period, bool) {
+	if len(txs) < 2 {
+		return period{}, false
+	}
+	intervals := make([]int, len(txs)-1)
+	for i := 1; i < len(txs); i++ {
+		intervals[i-1] = int(txs[i].Date.Sub(txs[i-1].Date).Hours() / 24)
+	}
+	median := medianInt(intervals)
+
+	for _, p := range periods {
+		if median < p.min || median > p.max || len(txs) < p.minCharge {
+			continue
+		}
+		fit := 0
+		for _, d := range intervals {
+			if d >= p.min && d <= p.max {
+				fit++
+			}
+		}
+		return p, float64(fit) >= minRegularShare*float64(len(intervals))
+	}
+	return period{}, false
+}
+
+func stableAmounts(txs []Transaction) bool {
+	amounts := make([]int, len(txs))
+	for i, t := range txs {
+		amounts[i] = int(-t.Amount)
+	}
+	median := float64(medianInt(amounts))
+
+	fit := 0
+	for _, a := range amounts {
+		if d := float64(a) - median; d <= amountTolerance*median && -d <= amountTolerance*median {
+			fit++
+		}
+	}
+	return float64(fit) >= minRegularShare*float64(len(amounts))
+}
+
+// merchantName выбирает название сервиса: самое частое имя получателя в группе
+// без номеров операций.
+func merchantName(txs []Transaction) string {
+	counts := map[string]int{}
+	best := ""
+	for _, t := range txs {
+		name := strings.Join(merchantWords(t.Merchant), " ")
+		counts[name]++
+		if counts[name] > counts[best] || (counts[name] == counts[best] && name < best) {
+			best = name
+		}
+	}
+	if r := []rune(best); len(r) > 200 {
+		best = string(r[:200])
+	}
+	return best
+}
+
+func medianInt(values []int) int {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	return sorted[len(sorted)/2]
+}
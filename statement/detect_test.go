@@ -0,0 +1,165 @@
+package statement
+
+import (
+	"testing"
+	"time"
+
+	"subscriptions-go/model"
+)
+
+// charges возвращает n списаний amount с шагом step, начиная с даты start.
+func charges(merchant string, amount int64, start string, n int, step func(time.Time) time.Time) []Transaction {
+	txs := make([]Transaction, n)
+	d := date(start)
+	for i := range txs {
+		txs[i] = Transaction{Date: d, Amount: -amount, Currency: "USD", Merchant: merchant}
+		d = step(d)
+	}
+	return txs
+}
+
+func everyDays(days int) func(time.Time) time.Time {
+	return func(t time.Time) time.Time { return t.AddDate(0, 0, days) }
+}
+
+func everyMonths(months int) func(time.Time) time.Time {
+	return func(t time.Time) time.Time { return t.AddDate(0, months, 0) }
+}
+
+func concat(parts ...[]Transaction) []Transaction {
+	var txs []Transaction
+	for _, p := range parts {
+		txs = append(txs, p...)
+	}
+	return txs
+}
+
+func TestDetect(t *testing.T) {
+	type want struct {
+		key         string
+		unit        string
+		count       int
+		amount      int64
+		occurrences int
+		ended       bool
+	}
+	tests := []struct {
+		name string
+		txs  []Transaction
+		want []want
+	}{
+		{
+			name: "monthly",
+			txs:  charges("NETFLIX.COM 866-579 CA", 1549, "2024-01-15", 6, everyMonths(1)),
+			want: []want{{"netflix", model.BillingMonth, 1, 1549, 6, false}},
+		},
+		{
+			name: "weekly",
+			txs:  charges("Weekly Box Delivery", 2500, "2024-01-01", 5, everyDays(7)),
+			want: []want{{"weekly box", model.BillingWeek, 1, 2500, 5, false}},
+		},
+		{
+			name: "quarterly",
+			txs:  charges("Cloud Storage Ltd", 2999, "2023-01-10", 4, everyMonths(3)),
+			want: []want{{"cloud storage", model.BillingMonth, 3, 2999, 4, false}},
+		},
+		{
+			name: "yearly",
+			txs:  charges("Domain Registrar", 1200, "2022-06-01", 3, everyMonths(12)),
+			want: []want{{"domain registrar", model.BillingYear, 1, 1200, 3, false}},
+		},
+		{
+			name: "price change and a missed month",
+			txs: concat(
+				charges("Spotify P1F2A3", 999, "2024-01-03", 3, everyMonths(1)),
+				charges("SPOTIFY P9Z8Y7", 1099, "2024-05-03", 3, everyMonths(1)),
+			),
+			want: []want{{"spotify", model.BillingMonth, 1, 1099, 6, false}},
+		},
+		{
+			name: "ended subscription",
+			txs: concat(
+				charges("Gym Membership", 4000, "2024-01-01", 4, everyMonths(1)),
+				charges("Salary", -300000, "2024-01-25", 10, everyMonths(1)),
+			),
+			want: []want{{"gym membership", model.BillingMonth, 1, 4000, 4, true}},
+		},
+		{
+			name: "noisy",
+			txs: []Transaction{
+				// разные суммы в случайные дни
+				{Date: date("2024-01-02"), Amount: -4312, Currency: "USD", Merchant: "Grocery Store"},
+				{Date: date("2024-01-09"), Amount: -1275, Currency: "USD", Merchant: "Grocery Store"},
+				{Date: date("2024-01-11"), Amount: -9820, Currency: "USD", Merchant: "Grocery Store"},
+				{Date: date("2024-02-20"), Amount: -2200, Currency: "USD", Merchant: "Grocery Store"},
+				{Date: date("2024-03-01"), Amount: -5600, Currency: "USD", Merchant: "Grocery Store"},
+				// регулярные даты, но суммы всё время разные
+				{Date: date("2024-01-05"), Amount: -1000, Currency: "USD", Merchant: "Taxi Service"},
+				{Date: date("2024-02-05"), Amount: -5000, Currency: "USD", Merchant: "Taxi Service"},
+				{Date: date("2024-03-05"), Amount: -300, Currency: "USD", Merchant: "Taxi Service"},
+				{Date: date("2024-04-05"), Amount: -8000, Currency: "USD", Merchant: "Taxi Service"},
+				// поступления не бывают подписками
+				{Date: date("2024-01-10"), Amount: 10000, Currency: "USD", Merchant: "Cashback Bonus"},
+				{Date: date("2024-02-10"), Amount: 10000, Currency: "USD", Merchant: "Cashback Bonus"},
+				{Date: date("2024-03-10"), Amount: 10000, Currency: "USD", Merchant: "Cashback Bonus"},
+				// только два ежемесячных списания — мало
+				{Date: date("2024-01-20"), Amount: -700, Currency: "USD", Merchant: "Trial App"},
+				{Date: date("2024-02-20"), Amount: -700, Currency: "USD", Merchant: "Trial App"},
+				// без значимых слов в имени
+				{Date: date("2024-01-07"), Amount: -500, Currency: "USD", Merchant: "POS 1234"},
+				{Date: date("2024-02-07"), Amount: -500, Currency: "USD", Merchant: "POS 1234"},
+				{Date: date("2024-03-07"), Amount: -500, Currency: "USD", Merchant: "POS 1234"},
+			},
+			want: nil,
+		},
+		{
+			name: "same merchant in two currencies",
+			txs: concat(
+				charges("Apple Music", 1099, "2024-01-01", 3, everyMonths(1)),
+				func() []Transaction {
+					txs := charges("Apple Music", 899, "2024-01-01", 3, everyMonths(1))
+					for i := range txs {
+						txs[i].Currency = "EUR"
+					}
+					return txs
+				}(),
+			),
+			want: []want{
+				{"apple music", model.BillingMonth, 1, 899, 3, false},
+				{"apple music", model.BillingMonth, 1, 1099, 3, false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Detect(tt.txs)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d candidates %+v, want %d", len(got), got, len(tt.want))
+			}
+			for i, w := range tt.want {
+				c := got[i]
+				if c.Key != w.key || c.BillingUnit != w.unit || c.BillingCount != w.count ||
+					c.Amount != w.amount || c.Occurrences != w.occurrences || c.Ended != w.ended {
+					t.Errorf("candidate %d = %+v, want %+v", i, c, w)
+				}
+			}
+		})
+	}
+}
+
+func TestMerchantKey(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"NETFLIX.COM 866-579 CA", "netflix"},
+		{"Netflix.com", "netflix"},
+		{"POS PURCHASE Spotify P1F2A3 Stockholm", "spotify stockholm"},
+		{"Яндекс Плюс 12345", "яндекс плюс"},
+		{"POS 1234", ""},
+	}
+	for _, tt := range tests {
+		if got := MerchantKey(tt.in); got != tt.want {
+			t.Errorf("MerchantKey(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
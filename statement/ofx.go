@@ -0,0 +1,89 @@
+package statement
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"subscriptions-go/money"
+)
+
+// ofxTag находит теги OFX вместе со значением до следующего тега. Подходит и для
+// SGML-версии 1.x, где у элементов нет закрывающих тегов, и для XML-версии 2.x.
+var ofxTag = regexp.MustCompile(`<(/?)([A-Za-z0-9.]+)>([^<]*)`)
+
+var ofxEntities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&nbsp;", " ", "&amp;", "&")
+
+// ParseOFX читает операции выписки OFX. Валюта берётся из CURDEF выписки, без него — currency.
+func ParseOFX(r io.Reader, currency string) ([]Transaction, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(strings.ToUpper(string(data)), "<OFX>") {
+		return nil, &Error{Field: "file", Message: "not an OFX document"}
+	}
+
+	var txs []Transaction
+	var tx map[string]string // элементы текущей STMTTRN
+	finish := func() error {
+		if tx == nil {
+			return nil
+		}
+		t, err := ofxTransaction(tx, currency)
+		if err != nil {
+			return err
+		}
+		txs = append(txs, t)
+		tx = nil
+		return nil
+	}
+
+	for _, m := range ofxTag.FindAllStringSubmatch(string(data), -1) {
+		closing, name, value := m[1] == "/", strings.ToUpper(m[2]), strings.TrimSpace(ofxEntities.Replace(m[3]))
+		switch {
+		case name == "STMTTRN" || closing && name == "BANKTRANLIST":
+			// в SGML закрывающий тег STMTTRN необязателен
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			if name == "STMTTRN" && !closing {
+				tx = map[string]string{}
+			}
+		case closing:
+		case name == "CURDEF":
+			currency = money.Normalize(value)
+		case tx != nil:
+			tx[name] = value
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+func ofxTransaction(tx map[string]string, currency string) (Transaction, error) {
+	t := Transaction{Currency: currency}
+
+	posted := tx["DTPOSTED"]
+	if len(posted) < 8 {
+		return t, &Error{Field: "file", Message: "transaction " + tx["FITID"] + ": DTPOSTED is missing"}
+	}
+	date, err := time.Parse("20060102", posted[:8])
+	if err != nil {
+		return t, &Error{Field: "file", Message: "transaction " + tx["FITID"] + ": invalid DTPOSTED " + posted}
+	}
+	t.Date = date
+
+	if t.Amount, err = parseDecimal(tx["TRNAMT"], currency); err != nil {
+		return t, &Error{Field: "file", Message: "transaction " + tx["FITID"] + ": " + err.Error()}
+	}
+
+	t.Merchant = tx["NAME"]
+	if t.Merchant == "" {
+		t.Merchant = tx["MEMO"]
+	}
+	return t, nil
+}
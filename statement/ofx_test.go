@@ -0,0 +1,130 @@
+package statement
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// OFX 1.x: SGML-заголовок, у элементов нет закрывающих тегов.
+const ofxSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+ENCODING:USASCII
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>EUR
+<BANKTRANLIST>
+<DTSTART>20240101
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240105120000[+1:CET]
+<TRNAMT>-9,99
+<FITID>1
+<NAME>SPOTIFY AB &amp; CO
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240110
+<TRNAMT>2500.00
+<FITID>2
+<MEMO>Salary
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+// OFX 2.x: XML с закрывающими тегами.
+const ofxXML = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+  <BANKMSGSRSV1><STMTTRNRS><STMTRS>
+    <CURDEF>USD</CURDEF>
+    <BANKTRANLIST>
+      <STMTTRN>
+        <TRNTYPE>DEBIT</TRNTYPE>
+        <DTPOSTED>20240201</DTPOSTED>
+        <TRNAMT>-15.49</TRNAMT>
+        <FITID>A1</FITID>
+        <NAME>NETFLIX.COM</NAME>
+      </STMTTRN>
+      <STMTTRN>
+        <TRNTYPE>DEBIT</TRNTYPE>
+        <DTPOSTED>20240215</DTPOSTED>
+        <TRNAMT>-1.000</TRNAMT>
+        <FITID>A2</FITID>
+        <MEMO>Coffee</MEMO>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		currency string
+		want     []Transaction
+	}{
+		{
+			name:     "SGML",
+			in:       ofxSGML,
+			currency: "RUB",
+			want: []Transaction{
+				{Date: date("2024-01-05"), Amount: -999, Currency: "EUR", Merchant: "SPOTIFY AB & CO"},
+				{Date: date("2024-01-10"), Amount: 250000, Currency: "EUR", Merchant: "Salary"},
+			},
+		},
+		{
+			name: "XML",
+			in:   ofxXML,
+			want: []Transaction{
+				{Date: date("2024-02-01"), Amount: -1549, Currency: "USD", Merchant: "NETFLIX.COM"},
+				{Date: date("2024-02-15"), Amount: -100, Currency: "USD", Merchant: "Coffee"},
+			},
+		},
+		{
+			name:     "currency from the request",
+			in:       strings.Replace(ofxXML, "<CURDEF>USD</CURDEF>", "", 1),
+			currency: "GBP",
+			want: []Transaction{
+				{Date: date("2024-02-01"), Amount: -1549, Currency: "GBP", Merchant: "NETFLIX.COM"},
+				{Date: date("2024-02-15"), Amount: -100, Currency: "GBP", Merchant: "Coffee"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOFX(strings.NewReader(tt.in), tt.currency)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOFXErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"not OFX", "date,amount,merchant\n"},
+		{"missing date", "<OFX><STMTTRN><TRNAMT>-1.00<FITID>1</OFX>"},
+		{"invalid date", "<OFX><STMTTRN><DTPOSTED>2024XX01<TRNAMT>-1.00<FITID>1</OFX>"},
+		{"invalid amount", "<OFX><STMTTRN><DTPOSTED>20240101<TRNAMT>1,000.00<FITID>1</OFX>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseOFX(strings.NewReader(tt.in), "USD")
+			var serr *Error
+			if !errors.As(err, &serr) || serr.Field != "file" {
+				t.Errorf("error = %v, want *Error for file", err)
+			}
+		})
+	}
+}
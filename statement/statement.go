@@ -0,0 +1,184 @@
+// Package statement читает банковские выписки (CSV, OFX, ISO 20022 camt.053)
+// и находит в них регулярные списания, похожие на подписки.
+package statement
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"subscriptions-go/money"
+)
+
+// Форматы выписок.
+const (
+	CSV     = "csv"
+	OFX     = "ofx"
+	CAMT053 = "camt053"
+)
+
+// Transaction — операция по счёту.
+type Transaction struct {
+	Date     time.Time
+	Amount   int64 // в минимальных единицах валюты; списания отрицательные
+	Currency string
+	Merchant string // получатель платежа или описание операции
+}
+
+// Error — ошибка в выписке. Line — номер строки CSV, 0 для ошибок файла целиком;
+// Field — поле запроса или столбец, к которому относится ошибка.
+type Error struct {
+	Line    int
+	Field   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
+}
+
+// Valid сообщает, поддерживается ли формат.
+func Valid(format string) bool {
+	return format == CSV || format == OFX || format == CAMT053
+}
+
+// FromMediaType определяет формат по Content-Type запроса или возвращает пустую строку.
+func FromMediaType(mediaType string) string {
+	switch strings.ToLower(mediaType) {
+	case "text/csv":
+		return CSV
+	case "application/x-ofx", "application/ofx":
+		return OFX
+	case "application/xml", "text/xml":
+		return CAMT053
+	}
+	return ""
+}
+
+// Parse читает выписку формата format. Для CSV используются opts; currency —
+// валюта операций, для которых выписка её не указывает.
+func Parse(format string, r io.Reader, opts CSVOptions, currency string) ([]Transaction, error) {
+	switch format {
+	case CSV:
+		if opts.Currency == "" {
+			opts.Currency = currency
+		}
+		return ParseCSV(r, opts)
+	case OFX:
+		return ParseOFX(r, currency)
+	case CAMT053:
+		return ParseCAMT053(r)
+	}
+	return nil, &Error{Field: "format", Message: "must be one of csv, ofx, camt053"}
+}
+
+// parseAmount переводит сумму из CSV в минимальные единицы валюты. Понимает
+// разделители разрядов и десятичную запятую ("1 234,50"), знак в начале или в конце
+// и сумму в скобках как отрицательную. Единственная точка или запятая, за которой
+// ровно три цифры ("1,000", "1.500"), в валюте с тремя знаками после запятой (KWD)
+// считается десятичной, в валюте без дробной части (JPY) — разделителем разрядов,
+// а в остальных валютах сумма неоднозначна и отклоняется.
+func parseAmount(s, currency string) (int64, error) {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\'' {
+			return -1
+		}
+		return r
+	}, s)
+
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")"):
+		neg, s = true, s[1:len(s)-1]
+	case strings.HasPrefix(s, "-"):
+		neg, s = true, s[1:]
+	case strings.HasSuffix(s, "-"):
+		neg, s = true, s[:len(s)-1]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	// десятичный разделитель — последняя точка или запятая, если она встречается один раз
+	whole, frac := s, ""
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 {
+		sep := s[i : i+1]
+		if strings.Count(s, sep) == 1 {
+			whole, frac = s[:i], s[i+1:]
+		}
+		if !strings.ContainsAny(whole, ".,") && thousands(whole, frac) {
+			switch money.Exponent(currency) {
+			case 3:
+			case 0:
+				whole, frac = whole+frac, ""
+			default:
+				return 0, fmt.Errorf("ambiguous amount %q: %q may separate thousands or decimals", s, sep)
+			}
+		}
+		whole = strings.NewReplacer(".", "", ",", "").Replace(whole)
+	}
+	return minorUnits(s, whole, frac, neg, currency)
+}
+
+// thousands сообщает, может ли единственный разделитель между whole и frac быть
+// разделителем разрядов: слева первая группа из 1–3 цифр, справа ровно три цифры.
+func thousands(whole, frac string) bool {
+	return len(frac) == 3 && len(whole) >= 1 && len(whole) <= 3 && whole[0] != '0' &&
+		digits(whole) && digits(frac)
+}
+
+// parseDecimal переводит в минимальные единицы валюты сумму из OFX или camt.053:
+// знак в начале, точка или запятая как десятичный разделитель, без разделителей разрядов.
+func parseDecimal(s, currency string) (int64, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg, s = true, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(strings.Replace(s, ",", ".", 1), ".")
+	return minorUnits(s, whole, frac, neg, currency)
+}
+
+// minorUnits собирает сумму в минимальных единицах валюты из целой и дробной частей.
+// s — исходная запись суммы для сообщений об ошибках.
+func minorUnits(s, whole, frac string, neg bool, currency string) (int64, error) {
+	if (whole == "" && frac == "") || !digits(whole) || !digits(frac) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	exp := money.Exponent(currency)
+	if len(frac) > exp {
+		if strings.Trim(frac[exp:], "0") != "" {
+			return 0, fmt.Errorf("amount %q has more decimals than %s allows", s, currency)
+		}
+		frac = frac[:exp]
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	v, err := strconv.ParseInt("0"+whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package statement
+
+import "testing"
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{"12.99", "USD", 1299, false},
+		{"12,99", "EUR", 1299, false},
+		{"-12.99", "USD", -1299, false},
+		{"12.99-", "USD", -1299, false},
+		{"(12.99)", "USD", -1299, false},
+		{"+5", "USD", 500, false},
+		{"1 234,50", "RUB", 123450, false},
+		{"1'234.50", "CHF", 123450, false},
+		{"1,234.50", "USD", 123450, false},
+		{"1.234,50", "EUR", 123450, false},
+		{"1,234,567", "USD", 123456700, false},
+		{"1.234.567,89", "EUR", 123456789, false},
+		{".5", "USD", 50, false},
+		{"0.500", "USD", 50, false},
+		{"12.500", "EUR", 0, true},
+		{"1,000", "USD", 0, true},
+		{"1.000", "EUR", 0, true},
+		{"-1,000", "USD", 0, true},
+		{"1,000.00", "USD", 100000, false},
+		{"1.500", "KWD", 1500, false},
+		{"1,500", "BHD", 1500, false},
+		{"-12.345", "TND", -12345, false},
+		{"1,234.567", "KWD", 1234567, false},
+		{"1000.000", "USD", 100000, false},
+		{"1.000", "JPY", 1000, false},
+		{"1000", "JPY", 1000, false},
+		{"1.5", "JPY", 0, true},
+		{"12.345", "USD", 0, true},
+		{"12.3456", "USD", 0, true},
+		{"", "USD", 0, true},
+		{"abc", "USD", 0, true},
+		{"1,2", "USD", 120, false},
+		{"12a", "USD", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.in, tt.currency)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAmount(%q, %s) error = %v, wantErr %v", tt.in, tt.currency, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseAmount(%q, %s) = %d, want %d", tt.in, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"-9.99", -999, false},
+		{"9,99", 999, false},
+		{"1.000", 100, false}, // в OFX и camt.053 разделителей разрядов нет
+		{"+15", 1500, false},
+		{" 4.5 ", 450, false},
+		{"1,000.00", 0, true},
+		{"1 000", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseDecimal(tt.in, "USD")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDecimal(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseDecimal(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}